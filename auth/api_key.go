@@ -7,6 +7,7 @@ import (
 
 	"github.com/kgrunwald/goweb/ctx"
 	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/openapi"
 )
 
 type APIKeyContext struct {
@@ -58,3 +59,8 @@ func (a *APIKeyScheme) Middleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// OpenAPISecurityScheme describes the x-api-key header checked by Authenticate.
+func (a *APIKeyScheme) OpenAPISecurityScheme() (string, openapi.SecurityScheme) {
+	return "apiKey", openapi.SecurityScheme{Type: "apiKey", In: "header", Name: "x-api-key"}
+}
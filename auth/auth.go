@@ -50,6 +50,7 @@ func HttpsMiddleware(log ilog.Logger, port int) router.Middleware {
 			if os.Getenv("ENV") != "local" && host != localhost && headerScheme != "https" && r.TLS == nil {
 				log.WithFields("host", host, "scheme", headerScheme).Info("Redirecting to HTTPS")
 				r.URL.Scheme = "https"
+				r.URL.Host = host
 				url := r.URL.String()
 				http.Redirect(w, r, url, http.StatusMovedPermanently)
 				return
@@ -8,6 +8,7 @@ import (
 
 	"github.com/kgrunwald/goweb/ctx"
 	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/openapi"
 	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
 )
@@ -135,3 +136,8 @@ func (j *JWTScheme) Middleware(next http.Handler) http.Handler {
 		next.ServeHTTP(context.Writer(), context.Request())
 	})
 }
+
+// OpenAPISecurityScheme describes the authorization cookie set by SetJWTCookie and checked by Authenticate.
+func (j *JWTScheme) OpenAPISecurityScheme() (string, openapi.SecurityScheme) {
+	return "jwt", openapi.SecurityScheme{Type: "apiKey", In: "cookie", Name: "authorization", Description: "JWT signed with HS256"}
+}
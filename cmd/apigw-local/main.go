@@ -0,0 +1,44 @@
+// Command apigw-local runs the example controllers behind a local API Gateway emulator. Every HTTP request is converted
+// into an API Gateway proxy event and served through the same Lambda code path used in production, including base64
+// bodies, stage variables and base path stripping.
+//
+// It is the example application started in goweb.ModeAPIGatewayLocal. Applications run their own routes the same way
+// by starting their binary with GO_API_MODE=apigw-local and LAMBDA_EMULATOR_PORT, or PORT, set.
+//
+//	go run ./cmd/apigw-local -port 3000 -stage dev -stage-vars "env=dev,table=items" -base-path v1
+package main
+
+import (
+	"flag"
+	"os"
+	"strconv"
+
+	"github.com/kgrunwald/goweb"
+	"github.com/kgrunwald/goweb/controller"
+	"github.com/kgrunwald/goweb/ilog"
+)
+
+func main() {
+	port := flag.Int("port", 3000, "port to listen on")
+	stage := flag.String("stage", goweb.DefaultEmulatorStage, "API Gateway stage name")
+	stageVars := flag.String("stage-vars", "", "comma separated key=value stage variables")
+	basePath := flag.String("base-path", "", "base path mapping to strip from request paths")
+	flag.Parse()
+
+	if _, err := goweb.ParseStageVars(*stageVars); err != nil {
+		ilog.Error(err.Error())
+		os.Exit(2)
+	}
+
+	os.Setenv(goweb.EnvMode, string(goweb.ModeAPIGatewayLocal))
+	os.Setenv(goweb.EnvEmulatorPort, strconv.Itoa(*port))
+	os.Setenv(goweb.EnvEmulatorStage, *stage)
+	os.Setenv(goweb.EnvEmulatorStageVars, *stageVars)
+	os.Setenv(goweb.EnvEmulatorBasePath, *basePath)
+	controller.Register()
+
+	if err := goweb.Start(); err != nil {
+		ilog.Error(err.Error())
+		os.Exit(1)
+	}
+}
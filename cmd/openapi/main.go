@@ -0,0 +1,35 @@
+// Command openapi prints the OpenAPI 3 document of the example controllers. It is the example application started in
+// goweb.ModeOpenAPI: applications export their own document the same way by starting their binary with
+// GO_API_MODE=openapi, or serve it by setting GO_API_OPENAPI_PATH.
+//
+//	go run ./cmd/openapi -title "Example API" -version 1.2.0 -server https://api.example.com > openapi.json
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/kgrunwald/goweb"
+	"github.com/kgrunwald/goweb/controller"
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/router"
+)
+
+func main() {
+	defaults := router.DefaultOpenAPIInfo()
+	title := flag.String("title", defaults.Title, "title of the API")
+	version := flag.String("version", defaults.Version, "version of the API")
+	server := flag.String("server", os.Getenv(goweb.EnvOpenAPIServer), "URL of the server the API is available at")
+	flag.Parse()
+
+	os.Setenv(goweb.EnvMode, string(goweb.ModeOpenAPI))
+	os.Setenv(router.OpenAPITitleVariable, *title)
+	os.Setenv(router.OpenAPIVersionVariable, *version)
+	os.Setenv(goweb.EnvOpenAPIServer, *server)
+	controller.Register()
+
+	if err := goweb.Start(); err != nil {
+		ilog.Error(err.Error())
+		os.Exit(1)
+	}
+}
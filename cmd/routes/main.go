@@ -0,0 +1,29 @@
+// Command routes lists the routes registered by the example controllers, as a table or as JSON. It is the example
+// application started in goweb.ModeRoutes: applications list their own routes the same way by starting their binary
+// with GO_API_MODE=routes. Committing the output lets route changes between releases show up in code review.
+//
+//	go run ./cmd/routes -format json > routes.json
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/kgrunwald/goweb"
+	"github.com/kgrunwald/goweb/controller"
+	"github.com/kgrunwald/goweb/ilog"
+)
+
+func main() {
+	format := flag.String("format", "table", "output format, table or json")
+	flag.Parse()
+
+	os.Setenv(goweb.EnvMode, string(goweb.ModeRoutes))
+	os.Setenv(goweb.EnvRoutesFormat, *format)
+	controller.Register()
+
+	if err := goweb.Start(); err != nil {
+		ilog.Error(err.Error())
+		os.Exit(1)
+	}
+}
@@ -37,9 +37,7 @@ func (t *T) Add(ctx ctx.Context, a, b int) error {
 	return errors.New("Test error message")
 }
 
-func (t *T) AddPost(ctx ctx.Context) error {
-	req := AddRequest{}
-	ctx.Bind(&req)
+func (t *T) AddPost(ctx ctx.Context, req *AddRequest) error {
 	res := map[string]int{"result": req.A + req.B}
 	return ctx.OK(res)
 }
@@ -2,12 +2,11 @@ package ctx
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/json"
 	"encoding/xml"
-	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/kgrunwald/goweb/apierrors"
 	"github.com/kgrunwald/goweb/ilog"
@@ -36,7 +35,11 @@ const ContentTypeTextXML = "text/xml"
 type Context interface {
 	// Request returns the underlying HTTP Request for this Context
 	Request() *http.Request
-	requestID() string
+
+	// RequestID returns the ID shared by every Context created for this request. It is sent to the client in the
+	// X-Request-ID header and added to every entry written through Log.
+	RequestID() string
+
 	Writer() http.ResponseWriter
 
 	AddValue(interface{}, interface{})
@@ -59,13 +62,43 @@ type Context interface {
 	BadRequest(interface{}) error
 	SendError(error) error
 	Log() ilog.Logger
+
+	// Done returns a channel that is closed when the request is cancelled, either because the client went away or
+	// because the deadline passed. On AWS Lambda the deadline is derived from the remaining time of the invocation.
+	Done() <-chan struct{}
+
+	// Deadline returns the time by which the request should be answered. ok is false when there is no deadline.
+	Deadline() (deadline time.Time, ok bool)
+
+	// Err returns the reason Done was closed, or nil if it is still open.
+	Err() error
+
+	// URL returns the path of the named route, with the variables of its template replaced by params given as
+	// name/value pairs, for example `c.URL("item", "id", "5")`. Base paths removed with Router.StripBasePath are added
+	// back so the path is valid for the client.
+	URL(name string, params ...string) (string, error)
+
+	// AbsoluteURL is like URL but includes the scheme and host, taken from $GO_API_HOST when it is set and from the
+	// request otherwise. Use it for Location headers and links in response bodies.
+	AbsoluteURL(name string, params ...string) (string, error)
 }
 
+// New creates a Context for the request. The request ID stored in the request context by WithRequestID is reused when
+// present, followed by a valid X-Request-ID request header. Otherwise a new ID is generated for this Context only; the
+// router stores one ID per request at the edge so that this is only the case outside of it.
 func New(r *http.Request, w http.ResponseWriter, log ilog.Logger) Context {
+	id, ok := RequestIDFromContext(r.Context())
+	if !ok {
+		id = r.Header.Get(HeaderRequestID)
+		if !ValidRequestID(id) {
+			id = NewRequestID()
+		}
+	}
+
 	c := &ctx{
 		req:    r,
 		writer: w,
-		id:     newUID(log),
+		id:     id,
 		log:    log,
 	}
 
@@ -74,15 +107,6 @@ func New(r *http.Request, w http.ResponseWriter, log ilog.Logger) Context {
 	return c
 }
 
-func newUID(log ilog.Logger) string {
-	b := make([]byte, 16)
-	_, err := rand.Read(b)
-	if err != nil {
-		log.WithField("error", err).Fatal("Failed to create new ID")
-	}
-	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
-}
-
 type Encoder interface {
 	Encode(interface{}) error
 }
@@ -191,7 +215,7 @@ func (c *ctx) Initialize() {
 }
 
 func (c *ctx) Respond(status int, body interface{}) error {
-	c.writer.Header().Set("requestID", c.requestID())
+	c.writer.Header().Set(HeaderRequestID, c.RequestID())
 	c.writer.Header().Set(HeaderContentType, c.responseType)
 	c.writer.WriteHeader(status)
 	if err, ok := body.(error); ok {
@@ -241,12 +265,12 @@ func (c *ctx) SendError(err error) error {
 	return c.Respond(http.StatusInternalServerError, msg)
 }
 
-func (c *ctx) requestID() string {
+func (c *ctx) RequestID() string {
 	return c.id
 }
 
 func (c *ctx) Log() ilog.Logger {
-	return c.log.WithField("requestID", c.requestID())
+	return c.log.WithField("requestID", c.RequestID())
 }
 
 func (c *ctx) AddValue(key interface{}, value interface{}) {
@@ -262,3 +286,15 @@ func (c *ctx) GetValue(key interface{}) interface{} {
 func (c *ctx) Writer() http.ResponseWriter {
 	return c.writer
 }
+
+func (c *ctx) Done() <-chan struct{} {
+	return c.req.Context().Done()
+}
+
+func (c *ctx) Deadline() (time.Time, bool) {
+	return c.req.Context().Deadline()
+}
+
+func (c *ctx) Err() error {
+	return c.req.Context().Err()
+}
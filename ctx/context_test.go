@@ -2,7 +2,9 @@ package ctx
 
 import (
 	"bytes"
+	"context"
 	"testing"
+	"time"
 
 	"net/http/httptest"
 
@@ -161,3 +163,49 @@ func (s *testSuite) TestBadRequest() {
 	ctx.BadRequest(7)
 	s.Equal(400, w.Code)
 }
+
+func (s *testSuite) TestDeadline() {
+	deadline := time.Now().Add(time.Minute)
+	parent, cancel := context.WithDeadline(context.Background(), deadline)
+	req := httptest.NewRequest("GET", "/", nil).WithContext(parent)
+	ctx := New(req, httptest.NewRecorder(), l)
+
+	d, ok := ctx.Deadline()
+	s.True(ok)
+	s.Equal(deadline, d)
+	s.NoError(ctx.Err())
+
+	cancel()
+	<-ctx.Done()
+	s.Equal(context.Canceled, ctx.Err())
+}
+
+func (s *testSuite) TestRequestID() {
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(WithRequestID(req.Context(), "from-context"))
+	req.Header.Set(HeaderRequestID, "from-header")
+	s.Equal("from-context", New(req, httptest.NewRecorder(), l).RequestID())
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderRequestID, "from-header")
+	s.Equal("from-header", New(req, httptest.NewRecorder(), l).RequestID())
+
+	req.Header.Set(HeaderRequestID, "not valid")
+	id := New(req, httptest.NewRecorder(), l).RequestID()
+	s.NotEqual("not valid", id)
+	s.True(ValidRequestID(id))
+}
+
+func (s *testSuite) TestRespondSetsRequestIDHeader() {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(HeaderRequestID, "abc")
+	w := httptest.NewRecorder()
+	New(req, w, l).OK("ok")
+	s.Equal("abc", w.Header().Get(HeaderRequestID))
+}
+
+func (s *testSuite) TestURLWithoutRouter() {
+	ctx := New(httptest.NewRequest("GET", "/", nil), httptest.NewRecorder(), l)
+	_, err := ctx.URL("item", "id", "5")
+	s.Error(err)
+}
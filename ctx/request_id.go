@@ -0,0 +1,52 @@
+package ctx
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// HeaderRequestID holds the name of the header that carries the request ID in requests and responses
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength bounds the length of request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of parent that carries the request ID. Every Context created for a request with this
+// context reuses the ID in its logger and in the X-Request-ID response header.
+func WithRequestID(parent context.Context, id string) context.Context {
+	return context.WithValue(parent, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored by WithRequestID.
+func RequestIDFromContext(c context.Context) (string, bool) {
+	id, ok := c.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// NewRequestID returns a new random request ID formatted like a UUID.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// ValidRequestID reports whether an ID received from a client is safe to reuse in logs and response headers. IDs must be
+// at most 128 printable ASCII characters.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package ctx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// URLBuilder builds the URLs of named routes. The router stores itself in the context of every request it serves so
+// that Context.URL and Context.AbsoluteURL can reach it.
+type URLBuilder interface {
+	// URL returns the path of the named route, with the variables of its template replaced by params given as
+	// name/value pairs.
+	URL(name string, params ...string) (*url.URL, error)
+
+	// AbsoluteURL returns the URL of the named route including the scheme and host the request was received on.
+	AbsoluteURL(req *http.Request, name string, params ...string) (*url.URL, error)
+}
+
+type urlBuilderKey struct{}
+
+// WithURLBuilder returns a copy of parent that carries the URLBuilder.
+func WithURLBuilder(parent context.Context, builder URLBuilder) context.Context {
+	return context.WithValue(parent, urlBuilderKey{}, builder)
+}
+
+func (c *ctx) urlBuilder() (URLBuilder, error) {
+	builder, ok := c.req.Context().Value(urlBuilderKey{}).(URLBuilder)
+	if !ok {
+		return nil, errors.New("The request was not served by a router that can build URLs")
+	}
+	return builder, nil
+}
+
+func (c *ctx) URL(name string, params ...string) (string, error) {
+	builder, err := c.urlBuilder()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := builder.URL(name, params...)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (c *ctx) AbsoluteURL(name string, params ...string) (string, error) {
+	builder, err := c.urlBuilder()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := builder.AbsoluteURL(c.req, name, params...)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
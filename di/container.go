@@ -11,6 +11,8 @@ type Container interface {
 	Get(name string) interface{}
 	New(name string) interface{}
 	GetMethod(service, method string) reflect.Value
+	Has(t reflect.Type) bool
+	Resolve(t reflect.Type) reflect.Value
 	Invoke(interface{})
 	Print()
 }
@@ -19,6 +21,10 @@ type Container interface {
 type ServiceContainer struct {
 	Services     map[string]interface{}
 	Constructors map[string]Constructor
+
+	// Types holds the exact type returned by the constructor of each service, so that `Foo` and `*Foo` are not
+	// mistaken for each other although they share an entry name.
+	Types map[string]reflect.Type
 }
 
 // A Constructor is a function that takes in the container as an argument, retrieves all necessary services, and returns
@@ -28,13 +34,16 @@ type Constructor func(*ServiceContainer) interface{}
 var svc *ServiceContainer
 
 func init() {
-	svc = newServiceContainer()
+	svc = NewServiceContainer()
 }
 
-func newServiceContainer() *ServiceContainer {
+// NewServiceContainer returns an empty container that only holds itself. Most applications use the shared container
+// from GetContainer, while tests can use their own to avoid sharing singletons.
+func NewServiceContainer() *ServiceContainer {
 	s := &ServiceContainer{
 		Services:     make(map[string]interface{}),
 		Constructors: make(map[string]Constructor),
+		Types:        make(map[string]reflect.Type),
 	}
 
 	// Add the container as a service in the container. Meta.
@@ -53,6 +62,7 @@ func (c *ServiceContainer) Register(ctor interface{}) {
 	ctorType := reflect.TypeOf(ctor)
 	returnType := ctorType.Out(0)
 	typeName := c.GetTypeName(returnType)
+	c.Types[typeName] = returnType
 	c.Constructors[typeName] = func(c *ServiceContainer) interface{} {
 		out := c.Call(ctor)
 		return out[0].Interface()
@@ -70,15 +80,29 @@ func (c *ServiceContainer) Call(f interface{}) []reflect.Value {
 	fType := reflect.TypeOf(f)
 	args := []reflect.Value{}
 	for i := 0; i < fType.NumIn(); i++ {
-		argType := fType.In(i)
-		argName := c.GetTypeName(argType)
-		argValue := c.GetValue(argName).Convert(argType)
-		args = append(args, argValue)
+		args = append(args, c.Resolve(fType.In(i)))
 	}
 
 	return reflect.ValueOf(f).Call(args)
 }
 
+// Has reports whether a service of exactly the given type has been registered in the container. A service registered
+// as `*Foo` does not satisfy `Foo`, and the other way around.
+func (c *ServiceContainer) Has(t reflect.Type) bool {
+	registered, ok := c.Types[c.GetTypeName(t)]
+	return ok && registered == t
+}
+
+// Resolve gets the service for the given type from the container. If the service is not in the container, or it is
+// registered with a different type such as a pointer to the requested type, the method will panic.
+func (c *ServiceContainer) Resolve(t reflect.Type) reflect.Value {
+	name := c.GetTypeName(t)
+	if registered, ok := c.Types[name]; ok && registered != t {
+		panic("Attempted to resolve service " + name + " as " + t.String() + ", but it is registered as " + registered.String() + ".")
+	}
+	return c.GetValue(name).Convert(t)
+}
+
 // GetTypeName generates the name of the service entry in the container from the Type.
 func (c *ServiceContainer) GetTypeName(t reflect.Type) string {
 	if t.Kind() == reflect.Ptr {
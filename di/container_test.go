@@ -20,7 +20,7 @@ type ContainerTestSuite struct {
 }
 
 func (s *ContainerTestSuite) SetupTest() {
-	svc = newServiceContainer()
+	svc = NewServiceContainer()
 }
 
 func TestContainerTestSuite(t *testing.T) {
@@ -101,3 +101,36 @@ func (s *ContainerTestSuite) TestInvoke() {
 		return false, nil
 	})
 }
+
+func (s *ContainerTestSuite) TestHas() {
+	c := GetContainer()
+	s.False(c.Has(reflect.TypeOf(newTestSvc())), "Service should not be registered")
+
+	c.Register(newTestSvc)
+	s.True(c.Has(reflect.TypeOf(newTestSvc())), "Service should be registered")
+}
+
+func (s *ContainerTestSuite) TestHasExactType() {
+	c := GetContainer()
+	c.Register(newTestSvc)
+	s.True(c.Has(reflect.TypeOf(&testSvc{})))
+	s.False(c.Has(reflect.TypeOf(testSvc{})), "Pointer service should not satisfy the value type")
+
+	c.Register(func() testSvc { return testSvc{} })
+	s.True(c.Has(reflect.TypeOf(testSvc{})))
+	s.False(c.Has(reflect.TypeOf(&testSvc{})), "Value service should not satisfy the pointer type")
+}
+
+func (s *ContainerTestSuite) TestResolvePanicsOnPointerMismatch() {
+	c := GetContainer()
+	c.Register(newTestSvc)
+	s.Panics(func() { c.Resolve(reflect.TypeOf(testSvc{})) })
+}
+
+func (s *ContainerTestSuite) TestResolve() {
+	c := GetContainer()
+	c.Register(newTestSvc)
+
+	v := c.Resolve(reflect.TypeOf(newTestSvc()))
+	s.EqualValues(c.Get(testSvcName), v.Interface(), "Resolve did not return singleton")
+}
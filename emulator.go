@@ -0,0 +1,114 @@
+package goweb
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kgrunwald/goweb/di"
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/pubsub"
+	"github.com/kgrunwald/goweb/router"
+)
+
+// Environment variables used to run a lambda-apigw-v1 application locally, either in ModeAPIGatewayLocal or in
+// ModeLambdaAPIGatewayV1 with $LAMBDA_EMULATOR_PORT set. Requests are converted into API Gateway proxy events for stage
+// $LAMBDA_EMULATOR_STAGE (default `local`) with the stage variables in $LAMBDA_EMULATOR_STAGE_VARS, given as comma
+// separated `key=value` pairs, after removing the base path mapping in $LAMBDA_EMULATOR_BASE_PATH.
+const (
+	EnvEmulatorPort      = "LAMBDA_EMULATOR_PORT"
+	EnvEmulatorStage     = "LAMBDA_EMULATOR_STAGE"
+	EnvEmulatorStageVars = "LAMBDA_EMULATOR_STAGE_VARS"
+	EnvEmulatorBasePath  = "LAMBDA_EMULATOR_BASE_PATH"
+)
+
+// DefaultEmulatorStage is the stage reported by the emulator when none is configured.
+const DefaultEmulatorStage = "local"
+
+// ServeLambdaEmulator serves the application on the given port through a local API Gateway emulator, exercising the
+// same code path as a lambda-apigw-v1 deployment. It blocks until the server shuts down, like Start.
+func ServeLambdaEmulator(port int, stage string, stageVars map[string]string) error {
+	var err error
+	di.GetContainer().Invoke(func(r router.Router, bus pubsub.Bus, log ilog.Logger, info *ServerInfo) {
+		emulated := *info
+		emulated.EmulatorPort = port
+		emulated.EmulatorStage = stage
+		emulated.EmulatorStageVars = stageVars
+		err = serveEmulator(r, bus, log, &emulated)
+	})
+	return err
+}
+
+// serveLocalEmulator serves ModeAPIGatewayLocal on $LAMBDA_EMULATOR_PORT, or on $PORT when it is not set.
+func serveLocalEmulator(r router.Router, bus pubsub.Bus, log ilog.Logger, info *ServerInfo) error {
+	emulated := *info
+	if emulated.EmulatorPort == 0 {
+		emulated.EmulatorPort = info.Port
+	}
+	if emulated.EmulatorPort == 0 {
+		return fmt.Errorf("$%s is %s but neither $%s nor $PORT is set", EnvMode, ModeAPIGatewayLocal, EnvEmulatorPort)
+	}
+	return serveEmulator(r, bus, log, &emulated)
+}
+
+func serveEmulator(r router.Router, bus pubsub.Bus, log ilog.Logger, info *ServerInfo) error {
+	if info.EmulatorBasePath != "" {
+		r.StripBasePath(info.EmulatorBasePath)
+	}
+
+	emulated := *info
+	emulated.Port = info.EmulatorPort
+	emulated.TLS = nil
+	emulated.RedirectPort = 0
+
+	log.WithFields("stage", info.EmulatorStage, "stageVariables", info.EmulatorStageVars).
+		Info(fmt.Sprintf("Emulating API Gateway on port %d", info.EmulatorPort))
+	emulator := router.NewAPIGatewayEmulator(r, log, info.EmulatorStage, info.EmulatorStageVars)
+	return serve(emulator, bus, log, &emulated)
+}
+
+func getEmulatorPort(log ilog.Logger) int {
+	val := os.Getenv(EnvEmulatorPort)
+	if val == "" {
+		return 0
+	}
+
+	port, err := strconv.Atoi(val)
+	if err != nil {
+		log.WithFields("variable", EnvEmulatorPort, "value", val).Fatal("Invalid emulator port")
+	}
+	return port
+}
+
+func getEmulatorStage() string {
+	if stage := os.Getenv(EnvEmulatorStage); stage != "" {
+		return stage
+	}
+	return DefaultEmulatorStage
+}
+
+func getEmulatorStageVars(log ilog.Logger) map[string]string {
+	vars, err := ParseStageVars(os.Getenv(EnvEmulatorStageVars))
+	if err != nil {
+		log.WithFields("variable", EnvEmulatorStageVars, "error", err).Fatal("Invalid stage variables")
+	}
+	return vars
+}
+
+// ParseStageVars parses comma separated `key=value` pairs into a map of stage variables.
+func ParseStageVars(val string) (map[string]string, error) {
+	vars := map[string]string{}
+	if strings.TrimSpace(val) == "" {
+		return vars, nil
+	}
+
+	for _, pair := range strings.Split(val, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("Stage variable %q must be in the form key=value", pair)
+		}
+		vars[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return vars, nil
+}
@@ -0,0 +1,21 @@
+package goweb
+
+import (
+	"testing"
+
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/stretchr/testify/suite"
+)
+
+type EmulatorTestSuite struct {
+	suite.Suite
+}
+
+func TestEmulatorTestSuite(t *testing.T) {
+	suite.Run(t, new(EmulatorTestSuite))
+}
+
+func (s *EmulatorTestSuite) TestLocalEmulatorRequiresPort() {
+	err := serveLocalEmulator(nil, nil, ilog.NewLogger(), &ServerInfo{Mode: ModeAPIGatewayLocal})
+	s.EqualError(err, "$GO_API_MODE is apigw-local but neither $LAMBDA_EMULATOR_PORT nor $PORT is set")
+}
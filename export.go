@@ -0,0 +1,80 @@
+package goweb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kgrunwald/goweb/openapi"
+	"github.com/kgrunwald/goweb/router"
+)
+
+// EnvOpenAPIServer is the environment variable that contains the URL of the server listed in the OpenAPI document
+// printed by ModeOpenAPI, such as `https://api.example.com`.
+const EnvOpenAPIServer = "GO_API_OPENAPI_SERVER"
+
+// EnvRoutesFormat is the environment variable that selects the format of the routes printed by ModeRoutes, either
+// `table`, the default, or `json`.
+const EnvRoutesFormat = "GO_API_ROUTES_FORMAT"
+
+// writeOpenAPI writes the JSON OpenAPI document of the router to w. The title and version are read from the variables
+// of router.DefaultOpenAPIInfo and the server from EnvOpenAPIServer.
+func writeOpenAPI(w io.Writer, r router.Router) error {
+	doc := r.OpenAPI(router.DefaultOpenAPIInfo())
+	if server := os.Getenv(EnvOpenAPIServer); server != "" {
+		doc.Servers = []openapi.Server{{URL: server}}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// writeRoutes writes the routes of the router to w in the format selected by EnvRoutesFormat.
+func writeRoutes(w io.Writer, r router.Router) error {
+	routes := r.Routes()
+	switch format := os.Getenv(EnvRoutesFormat); format {
+	case "", "table":
+		return writeRouteTable(w, routes)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(routes)
+	default:
+		return fmt.Errorf("Invalid $%s %q, expected table or json", EnvRoutesFormat, format)
+	}
+}
+
+func writeRouteTable(w io.Writer, routes []router.RouteInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tMETHODS\tPATH\tHEADERS\tHANDLER\tMIDDLEWARE")
+	for _, route := range routes {
+		methods := strings.Join(route.Methods, ",")
+		if methods == "" {
+			methods = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", dash(route.Name), methods, route.Path, dash(headerPairs(route.Headers)), route.Handler, dash(strings.Join(route.Middleware, ",")))
+	}
+	return tw.Flush()
+}
+
+// headerPairs formats the header matchers of a route as sorted name=value pairs.
+func headerPairs(h map[string]string) string {
+	pairs := make([]string, 0, len(h))
+	for name, value := range h {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
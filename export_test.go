@@ -0,0 +1,83 @@
+package goweb
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kgrunwald/goweb/ctx"
+	"github.com/kgrunwald/goweb/di"
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/router"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExportTestSuite struct {
+	suite.Suite
+	router router.Router
+}
+
+func TestExportTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportTestSuite))
+}
+
+func (s *ExportTestSuite) SetupTest() {
+	s.router = router.NewRouter(ilog.NewLogger(), di.GetContainer())
+	s.router.Route("/users/{id}", func(c ctx.Context, id int) {}).Methods("GET").Name("users.get")
+}
+
+func (s *ExportTestSuite) TestWriteOpenAPI() {
+	s.T().Setenv(router.OpenAPITitleVariable, "Users")
+	s.T().Setenv(router.OpenAPIVersionVariable, "2.0.0")
+	s.T().Setenv(EnvOpenAPIServer, "https://api.example.com")
+
+	var buf bytes.Buffer
+	s.Require().NoError(writeOpenAPI(&buf, s.router))
+
+	var doc struct {
+		Info struct {
+			Title   string
+			Version string
+		}
+		Servers []struct{ URL string }
+		Paths   map[string]map[string]interface{}
+	}
+	s.Require().NoError(json.Unmarshal(buf.Bytes(), &doc))
+	s.Equal("Users", doc.Info.Title)
+	s.Equal("2.0.0", doc.Info.Version)
+	s.Equal([]struct{ URL string }{{"https://api.example.com"}}, doc.Servers)
+	s.Contains(doc.Paths["/users/{id}"], "get")
+}
+
+func (s *ExportTestSuite) TestWriteRoutesTable() {
+	s.T().Setenv(EnvRoutesFormat, "")
+
+	var buf bytes.Buffer
+	s.Require().NoError(writeRoutes(&buf, s.router))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	s.Require().Len(lines, 2)
+	s.Equal([]string{"NAME", "METHODS", "PATH", "HEADERS", "HANDLER", "MIDDLEWARE"}, strings.Fields(lines[0]))
+	fields := strings.Fields(lines[1])
+	s.Equal([]string{"users.get", "GET", "/users/{id}", "-"}, fields[:4])
+}
+
+func (s *ExportTestSuite) TestWriteRoutesJSON() {
+	s.T().Setenv(EnvRoutesFormat, "json")
+
+	var buf bytes.Buffer
+	s.Require().NoError(writeRoutes(&buf, s.router))
+
+	var routes []router.RouteInfo
+	s.Require().NoError(json.Unmarshal(buf.Bytes(), &routes))
+	s.Require().Len(routes, 1)
+	s.Equal("users.get", routes[0].Name)
+	s.Equal("/users/{id}", routes[0].Path)
+	s.Equal([]string{"GET"}, routes[0].Methods)
+}
+
+func (s *ExportTestSuite) TestWriteRoutesInvalidFormat() {
+	s.T().Setenv(EnvRoutesFormat, "yaml")
+	s.Error(writeRoutes(&bytes.Buffer{}, s.router))
+}
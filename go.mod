@@ -1,17 +1,26 @@
 module github.com/kgrunwald/goweb
 
-go 1.15
+go 1.18
 
 require (
+	github.com/andybalholm/brotli v1.0.4
 	github.com/apex/log v1.1.0
-	github.com/aws/aws-lambda-go v1.23.0
-	github.com/fatih/color v1.7.0 // indirect
+	github.com/aws/aws-lambda-go v1.41.0
 	github.com/golang/mock v1.3.1
 	github.com/gorilla/mux v1.7.2
 	github.com/joho/godotenv v1.3.0
+	github.com/stretchr/testify v1.7.2
+	gopkg.in/square/go-jose.v2 v2.4.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/fatih/color v1.7.0 // indirect
 	github.com/mattn/go-colorable v0.1.2 // indirect
+	github.com/mattn/go-isatty v0.0.8 // indirect
 	github.com/pkg/errors v0.8.1 // indirect
-	github.com/stretchr/testify v1.6.1
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2 // indirect
 	golang.org/x/sys v0.0.0-20190422165155-953cdadca894 // indirect
-	gopkg.in/square/go-jose.v2 v2.4.1
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -1,13 +1,21 @@
+// Package goweb wires the router, the bus and the logger together. Applications register their routes and call Start,
+// and $GO_API_MODE selects how the binary runs: serving HTTP, serving events on AWS Lambda, or serving through the
+// local API Gateway emulator. In ModeOpenAPI it prints the OpenAPI document of the routes instead, configured by
+// EnvOpenAPIServer and the variables of router.DefaultOpenAPIInfo, and in ModeRoutes it lists the routes in the format
+// selected by EnvRoutesFormat. See Mode.
 package goweb
 
 import (
+	"crypto/tls"
 	"fmt"
 	"os"
-	"strconv"
+	"time"
 
+	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/joho/godotenv"
 	"github.com/kgrunwald/goweb/di"
 	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/openapi"
 	"github.com/kgrunwald/goweb/pubsub"
 	"github.com/kgrunwald/goweb/router"
 )
@@ -22,9 +30,44 @@ func init() {
 	})
 }
 
+// Default values for the HTTP server timeouts. Each may be overridden with a duration such as `30s` in the matching
+// environment variable.
+const (
+	DefaultReadTimeout     = 30 * time.Second
+	DefaultWriteTimeout    = 30 * time.Second
+	DefaultIdleTimeout     = 120 * time.Second
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
 type ServerInfo struct {
-	Port   int
+	Port int
+	Mode Mode
+
+	// Lambda is true when Mode runs on AWS Lambda.
 	Lambda bool
+
+	// ReadTimeout, WriteTimeout and IdleTimeout configure the http.Server. They are read from $READ_TIMEOUT,
+	// $WRITE_TIMEOUT and $IDLE_TIMEOUT.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	// ShutdownTimeout is the time allowed to drain in-flight requests, flush the bus and run shutdown hooks after
+	// SIGINT or SIGTERM is received. It is read from $SHUTDOWN_TIMEOUT.
+	ShutdownTimeout time.Duration
+
+	// TLS enables HTTPS with HTTP/2 when it is not nil. See EnvTLSCertFile and EnvTLSSelfSigned.
+	TLS *tls.Config
+
+	// RedirectPort starts a plain HTTP listener that redirects to HTTPS when it is not 0. Read from $HTTP_REDIRECT_PORT.
+	RedirectPort int
+
+	// EmulatorPort serves ModeLambdaAPIGatewayV1 through a local API Gateway emulator on this port instead of the Lambda
+	// runtime when it is not 0, and is the port of ModeAPIGatewayLocal. See EnvEmulatorPort.
+	EmulatorPort      int
+	EmulatorStage     string
+	EmulatorStageVars map[string]string
+	EmulatorBasePath  string
 }
 
 func (s *ServerInfo) String() string {
@@ -32,27 +75,84 @@ func (s *ServerInfo) String() string {
 }
 
 func getServerInfo(log ilog.Logger) *ServerInfo {
-	port, err := strconv.Atoi(os.Getenv("PORT"))
-	lambda := false
-	if err != nil {
-		lambda = true
-		log.Info("No $PORT variable found, assuming AWS Lambda")
+	mode, port := getMode(log)
+
+	return &ServerInfo{
+		Port:            port,
+		Mode:            mode,
+		Lambda:          mode.IsLambda(),
+		ReadTimeout:     durationFromEnv(log, "READ_TIMEOUT", DefaultReadTimeout),
+		WriteTimeout:    durationFromEnv(log, "WRITE_TIMEOUT", DefaultWriteTimeout),
+		IdleTimeout:     durationFromEnv(log, "IDLE_TIMEOUT", DefaultIdleTimeout),
+		ShutdownTimeout: durationFromEnv(log, "SHUTDOWN_TIMEOUT", DefaultShutdownTimeout),
+		TLS:             getTLSConfig(log),
+		RedirectPort:    getRedirectPort(log),
+
+		EmulatorPort:      getEmulatorPort(log),
+		EmulatorStage:     getEmulatorStage(),
+		EmulatorStageVars: getEmulatorStageVars(log),
+		EmulatorBasePath:  os.Getenv(EnvEmulatorBasePath),
+	}
+}
+
+func durationFromEnv(log ilog.Logger, name string, def time.Duration) time.Duration {
+	val := os.Getenv(name)
+	if val == "" {
+		return def
 	}
 
-	return &ServerInfo{port, lambda}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.WithFields("variable", name, "value", val, "default", def.String()).Warn("Invalid duration, using default")
+		return def
+	}
+	return d
 }
 
-// Start initializes all of the dependencies of the framework and starts listening for incoming HTTP requests
-func Start() {
+// Start initializes all of the dependencies of the framework and starts listening for incoming HTTP requests. It blocks
+// until SIGINT or SIGTERM is received and the server has shut down gracefully, or returns the error that caused the
+// listener to fail. Call it after the application has registered its routes: ModeOpenAPI and ModeRoutes print
+// the document or list describing them and return instead of serving requests.
+func Start() error {
+	var err error
 	c := di.GetContainer()
-	c.Invoke(func(r router.Router, log ilog.Logger, info *ServerInfo) {
+	c.Invoke(func(r router.Router, bus pubsub.Bus, log ilog.Logger, info *ServerInfo) {
 		if info.Lambda {
+			log.WithField("mode", info.Mode).Info("Starting AWS Lambda handler")
+		}
+
+		switch info.Mode {
+		case ModeHTTP:
+			scheme := "HTTP"
+			if info.TLS != nil {
+				scheme = "HTTPS"
+			}
+			log.Info(fmt.Sprintf("Listening for %s on port %d", scheme, info.Port))
+			err = serve(r, bus, log, info)
+		case ModeLambdaAPIGatewayV1:
+			if info.EmulatorPort != 0 {
+				err = serveEmulator(r, bus, log, info)
+				return
+			}
 			r.StartLambda()
-		} else {
-			log.Info(fmt.Sprintf("Listening on port %d", info.Port))
-			r.Start(info.Port)
+		case ModeLambdaAPIGatewayV2, ModeLambdaURL:
+			r.StartLambdaV2()
+		case ModeLambdaALB:
+			r.StartLambdaALB()
+		case ModeLambdaURLStream:
+			r.StartLambdaStream()
+		case ModeLambdaEvents:
+			lambda.Start(lambdaEventHandler(r, bus, log))
+		case ModeAPIGatewayLocal:
+			err = serveLocalEmulator(r, bus, log, info)
+		case ModeOpenAPI:
+			err = writeOpenAPI(os.Stdout, r)
+		case ModeRoutes:
+			err = writeRoutes(os.Stdout, r)
 		}
 	})
+
+	return err
 }
 
 func Route(path string, method interface{}) router.Route {
@@ -64,6 +164,41 @@ func Route(path string, method interface{}) router.Route {
 	return route
 }
 
+// Group registers the routes added by fn under the path prefix of the application router. See router.Router.Group.
+func Group(prefix string, fn func(router.Router)) router.Router {
+	var group router.Router
+	di.GetContainer().Invoke(func(r router.Router) {
+		group = r.Group(prefix, fn)
+	})
+
+	return group
+}
+
+// ServeOpenAPI serves the OpenAPI document of the application router at path. See router.Router.ServeOpenAPI.
+func ServeOpenAPI(path string, info openapi.Info) router.Route {
+	var route router.Route
+	di.GetContainer().Invoke(func(r router.Router) {
+		route = r.ServeOpenAPI(path, info)
+	})
+
+	return route
+}
+
+// Routes describes the routes of the application router. See router.Router.Routes.
+func Routes() []router.RouteInfo {
+	var routes []router.RouteInfo
+	di.GetContainer().Invoke(func(r router.Router) {
+		routes = r.Routes()
+	})
+
+	return routes
+}
+
+// RegisterConverter adds a custom path parameter Converter for the type of sample. See router.RegisterConverter.
+func RegisterConverter(sample interface{}, fn router.Converter) {
+	router.RegisterConverter(sample, fn)
+}
+
 func ServeSPA(pathPrefix, staticPath string) {
 	di.GetContainer().Invoke(func(r router.Router, log ilog.Logger) {
 		r.ServeSPA(pathPrefix, staticPath)
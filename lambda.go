@@ -0,0 +1,123 @@
+package goweb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/pubsub"
+	"github.com/kgrunwald/goweb/router"
+)
+
+// eventProbe holds the fields used to tell the supported Lambda event payloads apart.
+type eventProbe struct {
+	Version        string `json:"version"`
+	HTTPMethod     string `json:"httpMethod"`
+	DetailType     string `json:"detail-type"`
+	RequestContext struct {
+		ELB  json.RawMessage `json:"elb"`
+		HTTP json.RawMessage `json:"http"`
+	} `json:"requestContext"`
+	Records []struct {
+		// Matches both `eventSource` (SQS) and `EventSource` (SNS)
+		EventSource string `json:"eventSource"`
+	} `json:"Records"`
+}
+
+// decodeLambdaEvent detects the type of a raw Lambda payload and decodes it into the matching events struct.
+func decodeLambdaEvent(raw json.RawMessage) (interface{}, error) {
+	probe := eventProbe{}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, err
+	}
+
+	var event interface{}
+	switch {
+	case len(probe.Records) > 0 && probe.Records[0].EventSource == "aws:sqs":
+		event = &events.SQSEvent{}
+	case len(probe.Records) > 0 && probe.Records[0].EventSource == "aws:sns":
+		event = &events.SNSEvent{}
+	case probe.DetailType != "":
+		event = &events.CloudWatchEvent{}
+	case len(probe.RequestContext.ELB) > 0:
+		event = &events.ALBTargetGroupRequest{}
+	case probe.Version == "2.0" && len(probe.RequestContext.HTTP) > 0:
+		event = &events.APIGatewayV2HTTPRequest{}
+	case probe.HTTPMethod != "":
+		event = &events.APIGatewayProxyRequest{}
+	default:
+		return nil, errors.New("Unrecognized Lambda event payload")
+	}
+
+	if err := json.Unmarshal(raw, event); err != nil {
+		return nil, err
+	}
+	return derefEvent(event), nil
+}
+
+func derefEvent(event interface{}) interface{} {
+	switch e := event.(type) {
+	case *events.SQSEvent:
+		return *e
+	case *events.SNSEvent:
+		return *e
+	case *events.CloudWatchEvent:
+		return *e
+	case *events.ALBTargetGroupRequest:
+		return *e
+	case *events.APIGatewayV2HTTPRequest:
+		return *e
+	case *events.APIGatewayProxyRequest:
+		return *e
+	}
+	return event
+}
+
+// lambdaEventHandler returns a Lambda handler that serves HTTP events through the router and dispatches SQS, SNS,
+// EventBridge and scheduled CloudWatch events onto the bus. Each SQS message, SNS record and event is delivered
+// synchronously to the subscribers of its events type, such as `func(msg events.SQSMessage) error`.
+func lambdaEventHandler(r router.Router, bus pubsub.Bus, log ilog.Logger) func(context.Context, json.RawMessage) (interface{}, error) {
+	return func(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+		event, err := decodeLambdaEvent(raw)
+		if err != nil {
+			log.WithField("error", err).Error("Failed to decode Lambda event")
+			return nil, err
+		}
+
+		switch e := event.(type) {
+		case events.SQSEvent:
+			return dispatchSQS(bus, log, e), nil
+		case events.SNSEvent:
+			for _, record := range e.Records {
+				if err := bus.DispatchSync(record); err != nil {
+					log.WithFields("messageId", record.SNS.MessageID, "error", err).Error("Failed to handle SNS record")
+					return nil, err
+				}
+			}
+			return nil, nil
+		case events.CloudWatchEvent:
+			if err := bus.DispatchSync(e); err != nil {
+				log.WithFields("id", e.ID, "detailType", e.DetailType, "error", err).Error("Failed to handle event")
+				return nil, err
+			}
+			return nil, nil
+		}
+
+		return r.HandleLambda(ctx, event)
+	}
+}
+
+// dispatchSQS delivers every message of the batch to its subscribers and reports the messages that failed. Only these
+// messages are returned to the queue when the event source mapping has ReportBatchItemFailures enabled.
+func dispatchSQS(bus pubsub.Bus, log ilog.Logger, event events.SQSEvent) events.SQSEventResponse {
+	resp := events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{}}
+	for _, message := range event.Records {
+		if err := bus.DispatchSync(message); err != nil {
+			log.WithFields("messageId", message.MessageId, "error", err).Error("Failed to handle SQS message")
+			resp.BatchItemFailures = append(resp.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: message.MessageId})
+		}
+	}
+	return resp
+}
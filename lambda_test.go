@@ -0,0 +1,103 @@
+package goweb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/kgrunwald/goweb/ctx"
+	"github.com/kgrunwald/goweb/di"
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/pubsub"
+	"github.com/kgrunwald/goweb/router"
+	"github.com/stretchr/testify/suite"
+)
+
+type LambdaTestSuite struct {
+	suite.Suite
+	router  router.Router
+	bus     pubsub.Bus
+	handler func(context.Context, json.RawMessage) (interface{}, error)
+}
+
+func TestLambdaTestSuite(t *testing.T) {
+	suite.Run(t, new(LambdaTestSuite))
+}
+
+func (s *LambdaTestSuite) SetupTest() {
+	log := ilog.NewLogger()
+	s.router = router.NewRouter(log, di.GetContainer())
+	s.bus = pubsub.NewBus(log)
+	s.handler = lambdaEventHandler(s.router, s.bus, log)
+}
+
+func (s *LambdaTestSuite) TestDecodeLambdaEvent() {
+	cases := map[string]interface{}{
+		`{"Records": [{"eventSource": "aws:sqs", "messageId": "1"}]}`:                            events.SQSEvent{},
+		`{"Records": [{"EventSource": "aws:sns", "Sns": {"MessageId": "1"}}]}`:                   events.SNSEvent{},
+		`{"version": "0", "source": "aws.events", "detail-type": "Scheduled Event"}`:             events.CloudWatchEvent{},
+		`{"httpMethod": "GET", "path": "/", "requestContext": {"elb": {"targetGroupArn": "a"}}}`: events.ALBTargetGroupRequest{},
+		`{"version": "2.0", "rawPath": "/", "requestContext": {"http": {"method": "GET"}}}`:      events.APIGatewayV2HTTPRequest{},
+		`{"httpMethod": "GET", "path": "/", "requestContext": {"stage": "prod"}}`:                events.APIGatewayProxyRequest{},
+	}
+
+	for raw, expected := range cases {
+		event, err := decodeLambdaEvent(json.RawMessage(raw))
+		s.Require().NoError(err, raw)
+		s.IsType(expected, event, raw)
+	}
+
+	_, err := decodeLambdaEvent(json.RawMessage(`{"unknown": true}`))
+	s.Error(err)
+}
+
+func (s *LambdaTestSuite) TestSQSPartialBatchFailure() {
+	s.bus.Subscribe(func(msg events.SQSMessage) error {
+		if msg.Body == "bad" {
+			return errors.New("could not process")
+		}
+		return nil
+	})
+
+	raw := `{"Records": [
+		{"eventSource": "aws:sqs", "messageId": "1", "body": "good"},
+		{"eventSource": "aws:sqs", "messageId": "2", "body": "bad"}
+	]}`
+	resp, err := s.handler(context.Background(), json.RawMessage(raw))
+	s.Require().NoError(err)
+	s.Equal(events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{{ItemIdentifier: "2"}}}, resp)
+}
+
+func (s *LambdaTestSuite) TestScheduledEvent() {
+	received := ""
+	s.bus.Subscribe(func(e events.CloudWatchEvent) {
+		received = e.DetailType
+	})
+
+	_, err := s.handler(context.Background(), json.RawMessage(`{"source": "aws.events", "detail-type": "Scheduled Event"}`))
+	s.Require().NoError(err)
+	s.Equal("Scheduled Event", received)
+}
+
+func (s *LambdaTestSuite) TestSNSFailure() {
+	s.bus.Subscribe(func(r events.SNSEventRecord) error {
+		return errors.New("failed")
+	})
+
+	_, err := s.handler(context.Background(), json.RawMessage(`{"Records": [{"EventSource": "aws:sns", "Sns": {"Message": "hi"}}]}`))
+	s.Error(err)
+}
+
+func (s *LambdaTestSuite) TestHTTPEvent() {
+	s.router.Route("/ping", func(c ctx.Context) (string, error) {
+		return "pong", nil
+	})
+
+	resp, err := s.handler(context.Background(), json.RawMessage(`{"httpMethod": "GET", "path": "/ping"}`))
+	s.Require().NoError(err)
+	s.Equal(http.StatusOK, resp.(events.APIGatewayProxyResponse).StatusCode)
+	s.Equal("\"pong\"\n", resp.(events.APIGatewayProxyResponse).Body)
+}
@@ -0,0 +1,117 @@
+package goweb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/pubsub"
+)
+
+// ShutdownHook is invoked when the server shuts down. The context expires when the shutdown timeout elapses.
+type ShutdownHook func(ctx context.Context) error
+
+var (
+	hookLock      sync.Mutex
+	shutdownHooks []ShutdownHook
+)
+
+// OnShutdown registers a hook to run once the server has stopped accepting requests and the bus has been flushed. Hooks
+// run in the reverse order they were registered, so resources are released in the opposite order they were acquired.
+func OnShutdown(hook ShutdownHook) {
+	hookLock.Lock()
+	defer hookLock.Unlock()
+	shutdownHooks = append(shutdownHooks, hook)
+}
+
+// serve runs an http.Server for the handler until the listener fails or a termination signal is received. When TLS is
+// configured the server speaks HTTPS and HTTP/2, and an optional second listener redirects plain HTTP to it.
+func serve(handler http.Handler, bus pubsub.Bus, log ilog.Logger, info *ServerInfo) error {
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", info.Port),
+		Handler:      handler,
+		ReadTimeout:  info.ReadTimeout,
+		WriteTimeout: info.WriteTimeout,
+		IdleTimeout:  info.IdleTimeout,
+		TLSConfig:    info.TLS,
+	}
+	servers := []*http.Server{server}
+
+	errs := make(chan error, 2)
+	go func() {
+		if info.TLS != nil {
+			errs <- server.ListenAndServeTLS("", "")
+			return
+		}
+		errs <- server.ListenAndServe()
+	}()
+
+	if info.TLS != nil && info.RedirectPort != 0 {
+		redirect := &http.Server{
+			Addr:         fmt.Sprintf(":%d", info.RedirectPort),
+			Handler:      redirectHandler(info.Port),
+			ReadTimeout:  info.ReadTimeout,
+			WriteTimeout: info.WriteTimeout,
+			IdleTimeout:  info.IdleTimeout,
+		}
+		servers = append(servers, redirect)
+		log.Info(fmt.Sprintf("Redirecting HTTP on port %d to HTTPS", info.RedirectPort))
+		go func() {
+			errs <- redirect.ListenAndServe()
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-errs:
+		shutdown(servers, bus, log, info)
+		return err
+	case sig := <-signals:
+		log.WithField("signal", sig.String()).Info("Shutting down")
+	}
+
+	return shutdown(servers, bus, log, info)
+}
+
+// shutdown drains in-flight requests, flushes the bus and runs the shutdown hooks within the shutdown timeout. Every
+// step is attempted even if an earlier one fails, and the first error is returned.
+func shutdown(servers []*http.Server, bus pubsub.Bus, log ilog.Logger, info *ServerInfo) error {
+	ctx, cancel := context.WithTimeout(context.Background(), info.ShutdownTimeout)
+	defer cancel()
+
+	var result error
+	record := func(msg string, err error) {
+		if err == nil {
+			return
+		}
+		log.WithField("error", err).Error(msg)
+		if result == nil {
+			result = err
+		}
+	}
+
+	for _, server := range servers {
+		record("Failed to drain in-flight requests", server.Shutdown(ctx))
+	}
+	record("Failed to flush the event bus", bus.Flush(ctx))
+
+	hookLock.Lock()
+	hooks := make([]ShutdownHook, len(shutdownHooks))
+	copy(hooks, shutdownHooks)
+	hookLock.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		record("Shutdown hook failed", hooks[i](ctx))
+	}
+
+	log.Info("Shutdown complete")
+	return result
+}
@@ -0,0 +1,140 @@
+package goweb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeBus records when it is flushed and returns err from Flush.
+type fakeBus struct {
+	record func(string)
+	err    error
+}
+
+func (b *fakeBus) Subscribe(interface{})                  {}
+func (b *fakeBus) Dispatch(interface{})                   {}
+func (b *fakeBus) DispatchSync(message interface{}) error { return nil }
+
+func (b *fakeBus) Flush(ctx context.Context) error {
+	b.record("flush")
+	return b.err
+}
+
+type LifecycleTestSuite struct {
+	suite.Suite
+	lock  sync.Mutex
+	calls []string
+	info  *ServerInfo
+}
+
+func TestLifecycleTestSuite(t *testing.T) {
+	suite.Run(t, new(LifecycleTestSuite))
+}
+
+func (s *LifecycleTestSuite) SetupTest() {
+	hookLock.Lock()
+	shutdownHooks = nil
+	hookLock.Unlock()
+
+	s.calls = nil
+	s.info = &ServerInfo{ShutdownTimeout: 5 * time.Second}
+}
+
+func (s *LifecycleTestSuite) record(call string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls = append(s.calls, call)
+}
+
+func (s *LifecycleTestSuite) hook(name string, err error) ShutdownHook {
+	return func(ctx context.Context) error {
+		s.record(name)
+		return err
+	}
+}
+
+func (s *LifecycleTestSuite) TestShutdownOrder() {
+	OnShutdown(s.hook("first", nil))
+	OnShutdown(s.hook("second", nil))
+	OnShutdown(s.hook("third", nil))
+
+	err := shutdown(nil, &fakeBus{record: s.record}, ilog.NewLogger(), s.info)
+	s.NoError(err)
+	s.Equal([]string{"flush", "third", "second", "first"}, s.calls)
+}
+
+func (s *LifecycleTestSuite) TestShutdownFirstErrorWins() {
+	flushErr := errors.New("flush failed")
+	OnShutdown(s.hook("first", errors.New("first failed")))
+	OnShutdown(s.hook("second", errors.New("second failed")))
+
+	err := shutdown(nil, &fakeBus{record: s.record, err: flushErr}, ilog.NewLogger(), s.info)
+	s.Equal(flushErr, err)
+	s.Equal([]string{"flush", "second", "first"}, s.calls, "every step runs after a failure")
+
+	s.calls = nil
+	err = shutdown(nil, &fakeBus{record: s.record}, ilog.NewLogger(), s.info)
+	s.EqualError(err, "second failed")
+}
+
+func (s *LifecycleTestSuite) TestShutdownDrainsRequests() {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		s.record("request")
+		w.WriteHeader(http.StatusAccepted)
+	})}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	go server.Serve(listener)
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-started
+
+	OnShutdown(s.hook("hook", nil))
+	done := make(chan error, 1)
+	go func() {
+		done <- shutdown([]*http.Server{server}, &fakeBus{record: s.record}, ilog.NewLogger(), s.info)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	s.lock.Lock()
+	s.Empty(s.calls, "shutdown must wait for the in-flight request")
+	s.lock.Unlock()
+
+	close(release)
+	s.NoError(<-done)
+	s.Equal(http.StatusAccepted, <-status)
+	s.Equal([]string{"request", "flush", "hook"}, s.calls)
+}
+
+func (s *LifecycleTestSuite) TestShutdownTimeout() {
+	s.info.ShutdownTimeout = 10 * time.Millisecond
+	OnShutdown(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := shutdown(nil, &fakeBus{record: s.record}, ilog.NewLogger(), s.info)
+	s.Equal(context.DeadlineExceeded, err)
+}
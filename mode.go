@@ -0,0 +1,103 @@
+package goweb
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kgrunwald/goweb/ilog"
+)
+
+// EnvMode is the environment variable that selects how the application receives requests.
+const EnvMode = "GO_API_MODE"
+
+// Mode determines how the application receives requests.
+type Mode string
+
+const (
+	// ModeHTTP listens for HTTP requests on $PORT.
+	ModeHTTP Mode = "http"
+
+	// ModeLambdaAPIGatewayV1 serves API Gateway REST API proxy events (payload format 1.0) on AWS Lambda.
+	ModeLambdaAPIGatewayV1 Mode = "lambda-apigw-v1"
+
+	// ModeLambdaAPIGatewayV2 serves API Gateway HTTP API events (payload format 2.0) on AWS Lambda.
+	ModeLambdaAPIGatewayV2 Mode = "lambda-apigw-v2"
+
+	// ModeLambdaALB serves Application Load Balancer target group events on AWS Lambda.
+	ModeLambdaALB Mode = "lambda-alb"
+
+	// ModeLambdaURL serves Lambda Function URL events on AWS Lambda.
+	ModeLambdaURL Mode = "lambda-url"
+
+	// ModeLambdaURLStream serves Lambda Function URL events with the RESPONSE_STREAM invoke mode on AWS Lambda.
+	ModeLambdaURLStream Mode = "lambda-url-stream"
+
+	// ModeLambdaEvents detects the type of each event on AWS Lambda. HTTP events from any of the Lambda modes are served
+	// by the router, while SQS, SNS, EventBridge and scheduled events are dispatched to subscribers of the bus.
+	ModeLambdaEvents Mode = "lambda-events"
+
+	// ModeAPIGatewayLocal serves the routes through the local API Gateway emulator on $LAMBDA_EMULATOR_PORT, or on $PORT
+	// when it is not set, exercising the lambda-apigw-v1 code path without deploying. See EnvEmulatorStage.
+	ModeAPIGatewayLocal Mode = "apigw-local"
+
+	// ModeOpenAPI prints the JSON OpenAPI document of the routes registered by the application to stdout and exits,
+	// so that the document can be generated in a build step without starting the server. See EnvOpenAPIServer.
+	ModeOpenAPI Mode = "openapi"
+
+	// ModeRoutes prints the routes registered by the application to stdout and exits. Committing the output lets route
+	// changes between releases show up in code review. See EnvRoutesFormat.
+	ModeRoutes Mode = "routes"
+)
+
+var modes = []Mode{ModeHTTP, ModeLambdaAPIGatewayV1, ModeLambdaAPIGatewayV2, ModeLambdaALB, ModeLambdaURL, ModeLambdaURLStream, ModeLambdaEvents, ModeAPIGatewayLocal, ModeOpenAPI, ModeRoutes}
+
+// IsLambda reports whether the mode runs on AWS Lambda.
+func (m Mode) IsLambda() bool {
+	return strings.HasPrefix(string(m), "lambda-")
+}
+
+// ParseMode validates the name of a Mode.
+func ParseMode(val string) (Mode, error) {
+	names := make([]string, len(modes))
+	for i, m := range modes {
+		if string(m) == val {
+			return m, nil
+		}
+		names[i] = string(m)
+	}
+	return "", fmt.Errorf("Invalid $%s %q, expected one of: %s", EnvMode, val, strings.Join(names, ", "))
+}
+
+// getMode reads the Mode and port from the environment. When $GO_API_MODE is not set the mode is inferred from $PORT
+// for backwards compatibility: a valid port selects ModeHTTP, anything else selects ModeLambdaAPIGatewayV1. Invalid
+// configuration is fatal so that a typo does not leave the process waiting on the Lambda runtime API.
+func getMode(log ilog.Logger) (Mode, int) {
+	portVal := os.Getenv("PORT")
+	port, portErr := strconv.Atoi(portVal)
+
+	modeVal := os.Getenv(EnvMode)
+	if modeVal == "" {
+		if portErr == nil {
+			log.WithFields("PORT", portVal, "mode", ModeHTTP).
+				Warn(fmt.Sprintf("$%s is not set, assuming HTTP because $PORT is set. Set $%s explicitly to silence this warning", EnvMode, EnvMode))
+			return ModeHTTP, port
+		}
+
+		log.WithFields("PORT", portVal, "mode", ModeLambdaAPIGatewayV1).
+			Warn(fmt.Sprintf("$%s is not set and $PORT is missing or invalid, assuming AWS Lambda. Set $%s explicitly to silence this warning", EnvMode, EnvMode))
+		return ModeLambdaAPIGatewayV1, 0
+	}
+
+	mode, err := ParseMode(modeVal)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	if mode == ModeHTTP && portErr != nil {
+		log.WithField("PORT", portVal).Fatal(fmt.Sprintf("$%s is %s but $PORT is missing or not a number", EnvMode, ModeHTTP))
+	}
+
+	return mode, port
+}
@@ -0,0 +1,88 @@
+package goweb
+
+import (
+	"testing"
+
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/stretchr/testify/suite"
+)
+
+// levelLogger records the level of every message logged through it.
+type levelLogger struct {
+	levels *[]string
+}
+
+func newLevelLogger() levelLogger {
+	return levelLogger{levels: &[]string{}}
+}
+
+func (l levelLogger) Debug(string) { *l.levels = append(*l.levels, "debug") }
+func (l levelLogger) Info(string)  { *l.levels = append(*l.levels, "info") }
+func (l levelLogger) Warn(string)  { *l.levels = append(*l.levels, "warn") }
+func (l levelLogger) Error(string) { *l.levels = append(*l.levels, "error") }
+func (l levelLogger) Fatal(string) { *l.levels = append(*l.levels, "fatal") }
+
+func (l levelLogger) WithField(key string, value interface{}) ilog.Logger { return l }
+func (l levelLogger) WithFields(values ...interface{}) ilog.Logger        { return l }
+
+type ModeTestSuite struct {
+	suite.Suite
+}
+
+func TestModeTestSuite(t *testing.T) {
+	suite.Run(t, new(ModeTestSuite))
+}
+
+func (s *ModeTestSuite) TestParseMode() {
+	for _, mode := range modes {
+		parsed, err := ParseMode(string(mode))
+		s.NoError(err)
+		s.Equal(mode, parsed)
+	}
+
+	for _, val := range []string{"", "HTTP", "lambda", "lambda-apigw-v3"} {
+		_, err := ParseMode(val)
+		s.Error(err, val)
+	}
+}
+
+func (s *ModeTestSuite) TestIsLambda() {
+	for _, mode := range modes {
+		s.Equal(mode != ModeHTTP && mode != ModeAPIGatewayLocal && mode != ModeOpenAPI && mode != ModeRoutes, mode.IsLambda(), string(mode))
+	}
+}
+
+func (s *ModeTestSuite) TestGetMode() {
+	cases := []struct {
+		name     string
+		mode     string
+		port     string
+		wantMode Mode
+		wantPort int
+		levels   []string
+	}{
+		{"explicit http", "http", "8080", ModeHTTP, 8080, []string{}},
+		{"explicit lambda ignores port", "lambda-alb", "", ModeLambdaALB, 0, []string{}},
+		{"explicit lambda with port", "lambda-apigw-v2", "8080", ModeLambdaAPIGatewayV2, 8080, []string{}},
+		{"local emulator", "apigw-local", "8080", ModeAPIGatewayLocal, 8080, []string{}},
+		{"openapi ignores port", "openapi", "", ModeOpenAPI, 0, []string{}},
+		{"routes ignores port", "routes", "", ModeRoutes, 0, []string{}},
+		{"invalid mode", "lambda", "8080", "", 8080, []string{"fatal"}},
+		{"http without port", "http", "", ModeHTTP, 0, []string{"fatal"}},
+		{"http with invalid port", "http", "eighty", ModeHTTP, 0, []string{"fatal"}},
+		{"inferred http", "", "8080", ModeHTTP, 8080, []string{"warn"}},
+		{"inferred lambda", "", "", ModeLambdaAPIGatewayV1, 0, []string{"warn"}},
+		{"inferred lambda with invalid port", "", "eighty", ModeLambdaAPIGatewayV1, 0, []string{"warn"}},
+	}
+
+	for _, c := range cases {
+		s.T().Setenv(EnvMode, c.mode)
+		s.T().Setenv("PORT", c.port)
+		log := newLevelLogger()
+
+		mode, port := getMode(log)
+		s.Equal(c.wantMode, mode, c.name)
+		s.Equal(c.wantPort, port, c.name)
+		s.Equal(c.levels, *log.levels, c.name)
+	}
+}
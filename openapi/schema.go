@@ -0,0 +1,138 @@
+package openapi
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Ref returns a schema referencing the named component schema.
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// Schema returns the schema of values of type t as they are encoded to JSON. Named struct types are added to the
+// component schemas of the document, named after the type, and referenced. Fields follow the rules of encoding/json:
+// unexported fields and fields tagged `json:"-"` are skipped, the tag name replaces the field name and the fields of
+// embedded structs are promoted. time.Time, time.Duration and types implementing encoding.TextMarshaler are strings.
+func (d *Document) Schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		return d.Schema(t.Elem())
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "string", Format: "duration"}
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		min := 0.0
+		return &Schema{Type: "integer", Minimum: &min}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.Schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.Schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return Ref(d.component(t))
+	}
+
+	// Interfaces and any other kind may hold any value.
+	return &Schema{}
+}
+
+// ParameterSchema returns the schema of a path, query, header or cookie parameter of type t. Types implementing
+// encoding.TextUnmarshaler are strings, everything else is described like a JSON value.
+func (d *Document) ParameterSchema(t reflect.Type) *Schema {
+	if t != timeType && (reflect.PtrTo(t).Implements(textUnmarshalerType) || t.Implements(textUnmarshalerType)) {
+		return &Schema{Type: "string"}
+	}
+	return d.Schema(t)
+}
+
+// component adds the schema of the named struct type t to the components of the document and returns its name. Types
+// from different packages sharing a name are qualified with their package name.
+func (d *Document) component(t reflect.Type) string {
+	if name, ok := d.schemaNames[t]; ok {
+		return name
+	}
+	if d.schemaNames == nil {
+		d.schemaNames = map[reflect.Type]string{}
+	}
+
+	name := t.Name()
+	if _, taken := d.Components.Schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+
+	// Register the name before describing the fields so that recursive types reference themselves.
+	d.schemaNames[t] = name
+	d.Components.Schemas[name] = &Schema{}
+	*d.Components.Schemas[name] = *d.structSchema(t)
+	return name
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	d.addFields(s, t)
+	return s
+}
+
+func (d *Document) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			d.addFields(s, fieldType)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = d.Schema(field.Type)
+	}
+}
@@ -0,0 +1,72 @@
+package openapi
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SchemaTestSuite struct {
+	suite.Suite
+	doc *Document
+}
+
+func TestSchemaTestSuite(t *testing.T) {
+	suite.Run(t, new(SchemaTestSuite))
+}
+
+func (s *SchemaTestSuite) SetupTest() {
+	s.doc = New(Info{Title: "Test", Version: "1.0.0"})
+}
+
+type Audit struct {
+	Created time.Time `json:"created"`
+}
+
+type node struct {
+	Audit
+	Name     string            `json:"name,omitempty"`
+	Timeout  time.Duration     `json:"timeout"`
+	Data     []byte            `json:"data"`
+	Labels   map[string]string `json:"labels"`
+	Children []*node           `json:"children"`
+	Any      interface{}       `json:"any"`
+	Count    uint
+	Ignored  string `json:"-"`
+	internal string
+}
+
+func (s *SchemaTestSuite) TestStruct() {
+	s.Equal(Ref("node"), s.doc.Schema(reflect.TypeOf(&node{})))
+
+	min := 0.0
+	s.Equal(&Schema{Type: "object", Properties: map[string]*Schema{
+		"created":  {Type: "string", Format: "date-time"},
+		"name":     {Type: "string"},
+		"timeout":  {Type: "string", Format: "duration"},
+		"data":     {Type: "string", Format: "byte"},
+		"labels":   {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+		"children": {Type: "array", Items: Ref("node")},
+		"any":      {},
+		"Count":    {Type: "integer", Minimum: &min},
+	}}, s.doc.Components.Schemas["node"])
+}
+
+func (s *SchemaTestSuite) TestNameCollision() {
+	type Audit struct {
+		By string `json:"by"`
+	}
+
+	s.Equal(Ref("Audit"), s.doc.Schema(reflect.TypeOf(Audit{})))
+	s.Equal(Ref("openapi.Audit"), s.doc.Schema(reflect.TypeOf(node{}).Field(0).Type))
+	s.Equal(Ref("openapi.Audit"), s.doc.Schema(reflect.TypeOf(node{}).Field(0).Type))
+}
+
+func (s *SchemaTestSuite) TestParameterSchema() {
+	s.Equal(&Schema{Type: "string"}, s.doc.ParameterSchema(reflect.TypeOf(net.IP{})))
+	s.Equal(&Schema{Type: "string", Format: "date-time"}, s.doc.ParameterSchema(reflect.TypeOf(time.Time{})))
+	s.Equal(&Schema{Type: "boolean"}, s.doc.ParameterSchema(reflect.TypeOf(true)))
+}
@@ -1,7 +1,12 @@
 package pubsub
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 
 	"github.com/kgrunwald/goweb/di"
 	"github.com/kgrunwald/goweb/ilog"
@@ -14,6 +19,14 @@ import (
 type Bus interface {
 	Subscribe(interface{})
 	Dispatch(data interface{})
+
+	// Flush blocks until every dispatched message has been handled by all of its subscribers, or the context is done.
+	Flush(ctx context.Context) error
+
+	// DispatchSync invokes every subscriber of the message in the calling goroutine and waits for them to finish.
+	// Subscribers may return an error, and a panic in a subscriber is recovered and reported as an error. An error is
+	// also returned if the message has no subscribers, so that messages from a queue are not silently dropped.
+	DispatchSync(message interface{}) error
 }
 
 type queue chan interface{}
@@ -32,6 +45,7 @@ type eventBus struct {
 	Subscriptions []interface{}
 	Queue         queue
 	Logger        ilog.Logger
+	pending       sync.WaitGroup
 }
 
 func newEventBus(logger ilog.Logger) *eventBus {
@@ -50,9 +64,25 @@ func (e *eventBus) Subscribe(handler interface{}) {
 }
 
 func (e *eventBus) Dispatch(message interface{}) {
+	e.pending.Add(1)
 	e.Queue <- message
 }
 
+func (e *eventBus) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		e.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (e *eventBus) Run() {
 	go func() {
 		for {
@@ -65,19 +95,74 @@ func (e *eventBus) Run() {
 }
 
 func (e *eventBus) Publish(message interface{}) {
+	defer e.pending.Done()
+	for _, handler := range e.subscribers(message) {
+		e.Invoke(handler, message)
+	}
+}
+
+func (e *eventBus) Invoke(handler, message interface{}) {
+	handlerVal := reflect.ValueOf(handler)
+	msgVal := reflect.ValueOf(message)
+	e.pending.Add(1)
+	go func() {
+		defer e.pending.Done()
+		handlerVal.Call([]reflect.Value{msgVal})
+	}()
+}
+
+func (e *eventBus) DispatchSync(message interface{}) error {
+	handlers := e.subscribers(message)
+	if len(handlers) == 0 {
+		return fmt.Errorf("No subscribers for message of type %T", message)
+	}
+
+	var errs []string
+	for _, handler := range handlers {
+		if err := e.call(handler, message); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// subscribers returns the handlers whose argument type matches, or is an interface implemented by, the message type.
+func (e *eventBus) subscribers(message interface{}) []interface{} {
 	msgType := reflect.TypeOf(message)
+	handlers := []interface{}{}
 	for _, handler := range e.Subscriptions {
-		t := reflect.TypeOf(handler).In(0)
+		handlerType := reflect.TypeOf(handler)
+		if handlerType.NumIn() != 1 {
+			continue
+		}
+
+		t := handlerType.In(0)
 		if t == msgType {
-			e.Invoke(handler, message)
+			handlers = append(handlers, handler)
 		} else if t.Kind() == reflect.Interface && msgType.Implements(t) {
-			e.Invoke(handler, message)
+			handlers = append(handlers, handler)
 		}
 	}
+	return handlers
 }
 
-func (e *eventBus) Invoke(handler, message interface{}) {
-	handlerVal := reflect.ValueOf(handler)
-	msgVal := reflect.ValueOf(message)
-	go handlerVal.Call([]reflect.Value{msgVal})
+// call invokes the handler in the current goroutine, returning the error it returned or the value it panicked with.
+func (e *eventBus) call(handler, message interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Subscriber panicked: %v", r)
+		}
+	}()
+
+	out := reflect.ValueOf(handler).Call([]reflect.Value{reflect.ValueOf(message)})
+	if len(out) > 0 {
+		if err, ok := out[len(out)-1].Interface().(error); ok {
+			return err
+		}
+	}
+	return nil
 }
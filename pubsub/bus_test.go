@@ -1,8 +1,11 @@
 package pubsub
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/kgrunwald/goweb/ilog"
@@ -69,3 +72,57 @@ func (t *TestSuite) TestNewBus() {
 	b := NewBus(t.Logger)
 	t.Equal(reflect.TypeOf(&eventBus{}), reflect.TypeOf(b))
 }
+
+func (t *TestSuite) TestFlush() {
+	bus := newEventBus(t.Logger)
+
+	handled := make(chan bool, 1)
+	bus.Subscribe(func(msg *T) {
+		time.Sleep(10 * time.Millisecond)
+		handled <- true
+	})
+	bus.Dispatch(&T{})
+
+	t.NoError(bus.Flush(context.Background()))
+	t.Len(handled, 1)
+}
+
+func (t *TestSuite) TestFlushTimeout() {
+	bus := newEventBus(t.Logger)
+
+	block := make(chan bool)
+	defer close(block)
+	bus.Subscribe(func(msg *T) { <-block })
+	bus.Dispatch(&T{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	t.Equal(context.DeadlineExceeded, bus.Flush(ctx))
+}
+
+func (t *TestSuite) TestDispatchSync() {
+	bus := newEventBus(t.Logger)
+
+	calls := 0
+	bus.Subscribe(func(msg *T) { calls++ })
+	bus.Subscribe(func(msg Msg) error { calls++; return nil })
+
+	t.NoError(bus.DispatchSync(&T{}))
+	t.Equal(2, calls)
+}
+
+func (t *TestSuite) TestDispatchSyncErrors() {
+	bus := newEventBus(t.Logger)
+
+	bus.Subscribe(func(msg *T) error { return errors.New("failed") })
+	bus.Subscribe(func(msg Msg) { panic("boom") })
+
+	err := bus.DispatchSync(&T{})
+	t.EqualError(err, "failed; Subscriber panicked: boom")
+}
+
+func (t *TestSuite) TestDispatchSyncNoSubscribers() {
+	bus := newEventBus(t.Logger)
+
+	t.Error(bus.DispatchSync(&T{}))
+}
@@ -0,0 +1,174 @@
+package router
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/kgrunwald/goweb/ctx"
+)
+
+// Struct tags recognized on the fields of a parameter struct. A controller method argument whose struct type has at
+// least one field carrying one of these tags is populated from the request rather than from the path.
+const (
+	TagQuery  = "query"
+	TagHeader = "header"
+	TagCookie = "cookie"
+)
+
+var paramTags = []string{TagQuery, TagHeader, TagCookie}
+
+// FieldError describes a single request parameter that could not be bound to a controller argument.
+type FieldError struct {
+	Field   string `json:"field" xml:"field,attr"`
+	Source  string `json:"source" xml:"source,attr"`
+	Message string `json:"message" xml:",chardata"`
+}
+
+// BindErrorResponse is the body sent with a 400 response when one or more request parameters fail to convert.
+type BindErrorResponse struct {
+	XMLName xml.Name     `xml:"error" json:"-"`
+	Message string       `xml:"message" json:"error"`
+	Fields  []FieldError `xml:"field" json:"fields"`
+}
+
+// isParamsStruct reports whether t is a struct, or pointer to struct, with at least one field tagged with `query`,
+// `header` or `cookie`.
+func isParamsStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if _, _, ok := paramTag(t.Field(i)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func paramTag(field reflect.StructField) (source, name string, ok bool) {
+	for _, tag := range paramTags {
+		if name, ok := field.Tag.Lookup(tag); ok && name != "-" {
+			if name == "" {
+				name = field.Name
+			}
+			return tag, name, true
+		}
+	}
+	return "", "", false
+}
+
+// bindParams creates a new value of argType and fills every tagged field from the query string, headers or cookies of
+// the request using the same conversions as path parameters. Missing values leave the field at its zero value. All
+// conversion failures are collected so the client receives one message per offending field.
+func bindParams(r *http.Request, argType reflect.Type) (reflect.Value, *BindErrorResponse) {
+	structType := argType
+	if argType.Kind() == reflect.Ptr {
+		structType = argType.Elem()
+	}
+
+	out := reflect.New(structType)
+	query := r.URL.Query()
+	var fieldErrors []FieldError
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		source, name, ok := paramTag(field)
+		if !ok || field.PkgPath != "" {
+			continue
+		}
+
+		var values []string
+		switch source {
+		case TagQuery:
+			values = query[name]
+		case TagHeader:
+			values = r.Header.Values(name)
+		case TagCookie:
+			if cookie, err := r.Cookie(name); err == nil {
+				values = []string{cookie.Value}
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		val, err := convertValues(values, field.Type)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: name, Source: source, Message: err.Error()})
+			continue
+		}
+		out.Elem().Field(i).Set(val)
+	}
+
+	if len(fieldErrors) > 0 {
+		return reflect.Value{}, &BindErrorResponse{Message: "Invalid request parameters", Fields: fieldErrors}
+	}
+
+	if argType.Kind() == reflect.Ptr {
+		return out, nil
+	}
+	return out.Elem(), nil
+}
+
+// isBodyStruct reports whether t is a struct, or pointer to struct, that should be decoded from the request body.
+// Parameter structs are excluded, as are types the path conversions know how to handle such as time.Time.
+func isBodyStruct(t reflect.Type) bool {
+	structType := t
+	if t.Kind() == reflect.Ptr {
+		structType = t.Elem()
+	}
+	if structType.Kind() != reflect.Struct || isParamsStruct(t) {
+		return false
+	}
+	if _, ok := getConverter(t); ok {
+		return false
+	}
+	return !reflect.PtrTo(t).Implements(textUnmarshalerType) && !t.Implements(textUnmarshalerType)
+}
+
+// bindBody creates a new value of argType and decodes the request body into it using the decoder negotiated by the
+// Context from the Content-Type of the request.
+func bindBody(c ctx.Context, argType reflect.Type) (reflect.Value, error) {
+	structType := argType
+	if argType.Kind() == reflect.Ptr {
+		structType = argType.Elem()
+	}
+
+	out := reflect.New(structType)
+	if err := c.Bind(out.Interface()); err != nil {
+		if err == io.EOF {
+			return reflect.Value{}, errors.New("Request body is required")
+		}
+		return reflect.Value{}, fmt.Errorf("Invalid request body: %v", err)
+	}
+
+	if argType.Kind() == reflect.Ptr {
+		return out, nil
+	}
+	return out.Elem(), nil
+}
+
+// convertValues converts the values of a repeated parameter. Slice fields receive every value, any other type receives
+// the first one.
+func convertValues(values []string, t reflect.Type) (reflect.Value, error) {
+	if _, ok := getConverter(t); ok || t.Kind() != reflect.Slice {
+		return getArgument(values[0], t)
+	}
+
+	out := reflect.MakeSlice(t, 0, len(values))
+	for _, v := range values {
+		val, err := getArgument(v, t.Elem())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%q: %v", v, err)
+		}
+		out = reflect.Append(out, val)
+	}
+	return out, nil
+}
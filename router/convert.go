@@ -0,0 +1,143 @@
+package router
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Converter turns the raw string value of a request parameter into a value of a specific type. The returned value
+// must be assignable to the type the converter was registered for.
+type Converter func(string) (interface{}, error)
+
+var (
+	converterLock sync.RWMutex
+	converters    = map[reflect.Type]Converter{}
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// RegisterConverter adds a custom Converter for the type of the provided sample value. Controller methods may then
+// declare arguments of that type and they will be populated by calling the converter. Custom converters take precedence
+// over the built in conversions.
+//
+//	router.RegisterConverter(uuid.UUID{}, func(s string) (interface{}, error) {
+//		return uuid.Parse(s)
+//	})
+func RegisterConverter(sample interface{}, fn Converter) {
+	converterLock.Lock()
+	defer converterLock.Unlock()
+	converters[reflect.TypeOf(sample)] = fn
+}
+
+func getConverter(t reflect.Type) (Converter, bool) {
+	converterLock.RLock()
+	defer converterLock.RUnlock()
+	fn, ok := converters[t]
+	return fn, ok
+}
+
+// canConvert reports whether getArgument knows how to produce a value of the given type.
+func canConvert(t reflect.Type) bool {
+	if _, ok := getConverter(t); ok {
+		return true
+	}
+
+	if t == durationType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	if t.Kind() == reflect.Ptr && t.Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// getArgument converts the raw request value into a value of argType. Custom converters are consulted first, followed
+// by time.Duration, any type implementing encoding.TextUnmarshaler (which includes time.Time) and finally the
+// primitive kinds. Named types such as `type UserID string` are converted using their underlying kind.
+func getArgument(val string, argType reflect.Type) (reflect.Value, error) {
+	if fn, ok := getConverter(argType); ok {
+		out, err := fn(val)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		v := reflect.ValueOf(out)
+		if !v.IsValid() {
+			return reflect.Zero(argType), nil
+		}
+		if !v.Type().AssignableTo(argType) {
+			return reflect.Value{}, fmt.Errorf("Converter for %s returned %s", argType, v.Type())
+		}
+		return v, nil
+	}
+
+	if argType == durationType {
+		d, err := time.ParseDuration(val)
+		return reflect.ValueOf(d), err
+	}
+
+	if reflect.PtrTo(argType).Implements(textUnmarshalerType) {
+		v := reflect.New(argType)
+		if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val)); err != nil {
+			return reflect.Value{}, err
+		}
+		return v.Elem(), nil
+	}
+
+	if argType.Kind() == reflect.Ptr && argType.Implements(textUnmarshalerType) {
+		v := reflect.New(argType.Elem())
+		if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val)); err != nil {
+			return reflect.Value{}, err
+		}
+		return v, nil
+	}
+
+	v := reflect.New(argType).Elem()
+	switch argType.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(val, 10, argType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, argType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, argType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetFloat(f)
+	default:
+		return reflect.Value{}, fmt.Errorf("Failed to convert argType: %s", argType)
+	}
+
+	return v, nil
+}
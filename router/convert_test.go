@@ -0,0 +1,105 @@
+package router
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type userID string
+
+type slug struct {
+	value string
+}
+
+type ConvertTestSuite struct {
+	suite.Suite
+}
+
+func TestConvertTestSuite(t *testing.T) {
+	suite.Run(t, new(ConvertTestSuite))
+}
+
+func (s *ConvertTestSuite) convert(val string, sample interface{}) interface{} {
+	v, err := getArgument(val, reflect.TypeOf(sample))
+	s.Require().NoError(err)
+	return v.Interface()
+}
+
+func (s *ConvertTestSuite) TestPrimitives() {
+	s.Equal("abc", s.convert("abc", ""))
+	s.Equal(int(-5), s.convert("-5", int(0)))
+	s.Equal(int8(5), s.convert("5", int8(0)))
+	s.Equal(int32(5), s.convert("5", int32(0)))
+	s.Equal(int64(5), s.convert("5", int64(0)))
+	s.Equal(uint(5), s.convert("5", uint(0)))
+	s.Equal(uint16(5), s.convert("5", uint16(0)))
+	s.Equal(uint64(5), s.convert("5", uint64(0)))
+	s.Equal(float32(1.5), s.convert("1.5", float32(0)))
+	s.Equal(float64(1.5), s.convert("1.5", float64(0)))
+	s.Equal(true, s.convert("true", false))
+}
+
+func (s *ConvertTestSuite) TestNamedType() {
+	s.Equal(userID("u-1"), s.convert("u-1", userID("")))
+}
+
+func (s *ConvertTestSuite) TestTime() {
+	expected := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.Equal(expected, s.convert("2020-01-02T03:04:05Z", time.Time{}))
+	s.Equal(90*time.Second, s.convert("1m30s", time.Duration(0)))
+}
+
+func (s *ConvertTestSuite) TestTextUnmarshalerPointer() {
+	ip := s.convert("10.0.0.1", &net.IP{}).(*net.IP)
+	s.Equal("10.0.0.1", ip.String())
+}
+
+func (s *ConvertTestSuite) TestOverflow() {
+	_, err := getArgument("300", reflect.TypeOf(int8(0)))
+	s.Error(err)
+	_, err = getArgument("-1", reflect.TypeOf(uint(0)))
+	s.Error(err)
+}
+
+func (s *ConvertTestSuite) TestUnsupportedType() {
+	_, err := getArgument("x", reflect.TypeOf([]string{}))
+	s.Error(err)
+}
+
+// unregisterConverter removes the converter a test registered for the type of sample, so that it does not leak into
+// later tests.
+func unregisterConverter(sample interface{}) {
+	converterLock.Lock()
+	defer converterLock.Unlock()
+	delete(converters, reflect.TypeOf(sample))
+}
+
+func (s *ConvertTestSuite) TestCustomConverter() {
+	RegisterConverter(slug{}, func(val string) (interface{}, error) {
+		if strings.ContainsAny(val, " /") {
+			return nil, errors.New("invalid slug")
+		}
+		return slug{val}, nil
+	})
+	defer unregisterConverter(slug{})
+
+	s.Equal(slug{"my-post"}, s.convert("my-post", slug{}))
+	_, err := getArgument("my post", reflect.TypeOf(slug{}))
+	s.Error(err)
+}
+
+func (s *ConvertTestSuite) TestCustomConverterWrongType() {
+	RegisterConverter(userID(""), func(val string) (interface{}, error) {
+		return 5, nil
+	})
+	defer unregisterConverter(userID(""))
+
+	_, err := getArgument("x", reflect.TypeOf(userID("")))
+	s.Error(err)
+}
@@ -0,0 +1,235 @@
+package router
+
+import (
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kgrunwald/goweb/ctx"
+)
+
+// CORSOriginsVariable is the name of the environment variable that contains a comma separated list of the origins
+// allowed by DefaultCORSPolicy, such as `https://app.example.com,https://*.example.com`.
+const CORSOriginsVariable = "GO_API_CORS_ORIGINS"
+
+const (
+	headerOrigin                        = "Origin"
+	headerAccessControlRequestMethod    = "Access-Control-Request-Method"
+	headerAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
+	headerAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	headerAccessControlAllowMethods     = "Access-Control-Allow-Methods"
+	headerAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
+	headerAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
+	headerAccessControlMaxAge           = "Access-Control-Max-Age"
+)
+
+// routeMethods are the methods checked when looking for the methods a path accepts.
+var routeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// CORSPolicy configures the Cross-Origin Resource Sharing headers returned for a router or a route. Requests from an
+// origin that is not allowed receive no CORS headers, and their preflight requests are rejected with 403 Forbidden.
+type CORSPolicy struct {
+	// AllowedOrigins lists the allowed origins. An entry is either an exact origin such as `https://app.example.com`,
+	// a wildcard subdomain such as `https://*.example.com` that matches any subdomain but not the domain itself, or `*`
+	// which allows every origin without credentials.
+	AllowedOrigins []string
+
+	// AllowedOriginPatterns allows every origin matching one of the regular expressions. Patterns should be anchored.
+	AllowedOriginPatterns []*regexp.Regexp
+
+	// AllowedHeaders lists the request headers a preflight request may ask for.
+	AllowedHeaders []string
+
+	// ExposedHeaders lists the response headers scripts are allowed to read.
+	ExposedHeaders []string
+
+	// AllowCredentials allows cookies and authorization headers to be sent with requests from allowed origins. It
+	// never applies to origins that are only allowed by `*`.
+	AllowCredentials bool
+
+	// MaxAge is how long the result of a preflight request may be cached.
+	MaxAge time.Duration
+}
+
+// DefaultCORSPolicy returns the policy used by Router.EnableCORS. It allows the origins listed in CORSOriginsVariable
+// to send credentials with the Authorization, X-API-Key, Content-Type and X-Request-ID headers and to read the
+// X-Request-ID response header. No origin is allowed when the variable is empty.
+func DefaultCORSPolicy() CORSPolicy {
+	policy := CORSPolicy{
+		AllowedHeaders:   []string{"Authorization", "X-API-Key", ctx.HeaderContentType, ctx.HeaderRequestID},
+		ExposedHeaders:   []string{ctx.HeaderRequestID},
+		AllowCredentials: true,
+		MaxAge:           24 * time.Hour,
+	}
+
+	for _, o := range strings.Split(os.Getenv(CORSOriginsVariable), ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			policy.AllowedOrigins = append(policy.AllowedOrigins, o)
+		}
+	}
+	return policy
+}
+
+// allowOrigin returns the value of the Access-Control-Allow-Origin header for the origin, and false if the origin is
+// not allowed. Origins are compared without regard to case.
+func (p CORSPolicy) allowOrigin(origin string) (string, bool) {
+	wildcard := false
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" {
+			wildcard = true
+		} else if matchOrigin(allowed, origin) {
+			return origin, true
+		}
+	}
+
+	for _, pattern := range p.AllowedOriginPatterns {
+		if pattern.MatchString(origin) {
+			return origin, true
+		}
+	}
+
+	if wildcard {
+		return "*", true
+	}
+	return "", false
+}
+
+// matchOrigin reports whether the origin equals allowed or, when allowed has the form `scheme://*.domain`, whether it
+// is a subdomain of domain with the same scheme and port.
+func matchOrigin(allowed, origin string) bool {
+	allowed = strings.ToLower(allowed)
+	origin = strings.ToLower(origin)
+	if allowed == origin {
+		return true
+	}
+
+	i := strings.Index(allowed, "://*.")
+	if i < 0 {
+		return false
+	}
+	scheme, suffix := allowed[:i+3], allowed[i+4:]
+	if !strings.HasPrefix(origin, scheme) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+
+	subdomain := origin[len(scheme) : len(origin)-len(suffix)]
+	if subdomain == "" {
+		return false
+	}
+	for _, c := range subdomain {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// handleCORS adds the CORS headers for a request carrying an Origin header according to the policy of the route it
+// matches. Preflight requests are answered here and true is returned. Requests for paths without a route are left to
+// the router so that they receive the usual 404 response.
+func (r *muxRouter) handleCORS(w http.ResponseWriter, req *http.Request) bool {
+	preflight := req.Method == http.MethodOptions && req.Header.Get(headerAccessControlRequestMethod) != ""
+	method := req.Method
+	if preflight {
+		method = req.Header.Get(headerAccessControlRequestMethod)
+	}
+
+	policy, methods := r.matchCORSPolicy(req, method)
+	if policy == nil {
+		return false
+	}
+
+	h := w.Header()
+	h.Add("Vary", headerOrigin)
+	origin, ok := policy.allowOrigin(req.Header.Get(headerOrigin))
+	if !ok {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	h.Set(headerAccessControlAllowOrigin, origin)
+	if policy.AllowCredentials && origin != "*" {
+		h.Set(headerAccessControlAllowCredentials, "true")
+	}
+	if !preflight {
+		if len(policy.ExposedHeaders) > 0 {
+			h.Set(headerAccessControlExposeHeaders, strings.Join(policy.ExposedHeaders, ", "))
+		}
+		return false
+	}
+
+	h.Set(headerAccessControlAllowMethods, strings.Join(methods, ", "))
+	if len(policy.AllowedHeaders) > 0 {
+		h.Set(headerAccessControlAllowHeaders, strings.Join(policy.AllowedHeaders, ", "))
+	}
+	if policy.MaxAge > 0 {
+		h.Set(headerAccessControlMaxAge, strconv.Itoa(int(policy.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// matchCORSPolicy returns the policy of the route serving the path of the request with the given method, together with
+// every method the path accepts. When the method is not accepted the policy of the first matching route is returned so
+// that preflight requests still learn the allowed methods. The policy is nil if no route matches the path or no policy
+// applies to the route.
+func (r *muxRouter) matchCORSPolicy(req *http.Request, method string) (*CORSPolicy, []string) {
+	methods := r.allowedMethods(req)
+	if len(methods) == 0 {
+		return nil, nil
+	}
+
+	var match mux.RouteMatch
+	m := req.WithContext(req.Context())
+	m.Method = method
+	if !r.mux.Match(m, &match) || match.MatchErr != nil {
+		m.Method = methods[0]
+		match = mux.RouteMatch{}
+		r.mux.Match(m, &match)
+	}
+
+	if route, ok := r.registry.routes[match.Route]; ok {
+		return route.corsPolicy(), methods
+	}
+	return r.inheritedCORS(), methods
+}
+
+// allowedMethods returns the methods accepted by the routes matching the path of the request.
+func (r *muxRouter) allowedMethods(req *http.Request) []string {
+	var methods []string
+	for _, method := range routeMethods {
+		var match mux.RouteMatch
+		m := req.WithContext(req.Context())
+		m.Method = method
+		if r.mux.Match(m, &match) && match.MatchErr == nil && match.Handler != nil {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// inheritedCORS returns the policy of the router, inherited from the closest parent that has one.
+func (r *muxRouter) inheritedCORS() *CORSPolicy {
+	for router := r; router != nil; router = router.parent {
+		if router.cors != nil {
+			return router.cors
+		}
+	}
+	return nil
+}
+
+// corsPolicy returns the policy of the route, falling back to the policy of the router that created it.
+func (r *muxRoute) corsPolicy() *CORSPolicy {
+	if r.cors != nil {
+		return r.cors
+	}
+	return r.router.inheritedCORS()
+}
@@ -0,0 +1,150 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/kgrunwald/goweb/ctx"
+	"github.com/kgrunwald/goweb/di"
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/stretchr/testify/suite"
+)
+
+type CORSTestSuite struct {
+	suite.Suite
+	router Router
+}
+
+func TestCORSTestSuite(t *testing.T) {
+	suite.Run(t, new(CORSTestSuite))
+}
+
+func (s *CORSTestSuite) SetupTest() {
+	s.router = NewRouter(ilog.NewLogger(), di.GetContainer())
+	s.router.CORS(CORSPolicy{
+		AllowedOrigins:        []string{"https://app.example.com", "https://*.example.org"},
+		AllowedOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^https://pr-[0-9]+\.preview\.dev$`)},
+		AllowedHeaders:        []string{"Authorization", "Content-Type"},
+		ExposedHeaders:        []string{ctx.HeaderRequestID},
+		AllowCredentials:      true,
+		MaxAge:                time.Hour,
+	})
+	s.router.Route("/items", func(c ctx.Context) {}).Methods("GET", "POST")
+	s.router.Route("/items/{id}", func(c ctx.Context, id int) {}).Methods("DELETE")
+	s.router.Route("/public", func(c ctx.Context) {}).Methods("GET").CORS(CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+func (s *CORSTestSuite) request(method, path, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(headerOrigin, origin)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *CORSTestSuite) preflight(path, origin, method string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("OPTIONS", path, nil)
+	req.Header.Set(headerOrigin, origin)
+	req.Header.Set(headerAccessControlRequestMethod, method)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *CORSTestSuite) TestAllowedOrigins() {
+	for _, origin := range []string{"https://app.example.com", "https://a.b.example.org", "https://pr-12.preview.dev"} {
+		w := s.request("GET", "/items", origin)
+		s.Equal(http.StatusOK, w.Code)
+		s.Equal(origin, w.Header().Get(headerAccessControlAllowOrigin), origin)
+		s.Equal("true", w.Header().Get(headerAccessControlAllowCredentials))
+		s.Equal(ctx.HeaderRequestID, w.Header().Get(headerAccessControlExposeHeaders))
+		s.Equal(headerOrigin, w.Header().Get("Vary"))
+	}
+}
+
+func (s *CORSTestSuite) TestRejectedOrigins() {
+	for _, origin := range []string{"https://evil.com", "https://example.org", "http://a.example.org", "https://app.example.com.evil.com", "https://pr-x.preview.dev"} {
+		w := s.request("GET", "/items", origin)
+		s.Equal(http.StatusOK, w.Code)
+		s.Empty(w.Header().Get(headerAccessControlAllowOrigin), origin)
+		s.Empty(w.Header().Get(headerAccessControlAllowCredentials), origin)
+
+		w = s.preflight("/items", origin, "GET")
+		s.Equal(http.StatusForbidden, w.Code, origin)
+	}
+}
+
+func (s *CORSTestSuite) TestPreflight() {
+	w := s.preflight("/items", "https://app.example.com", "POST")
+	s.Equal(http.StatusNoContent, w.Code)
+	s.Equal("https://app.example.com", w.Header().Get(headerAccessControlAllowOrigin))
+	s.Equal("GET, POST", w.Header().Get(headerAccessControlAllowMethods))
+	s.Equal("Authorization, Content-Type", w.Header().Get(headerAccessControlAllowHeaders))
+	s.Equal("3600", w.Header().Get(headerAccessControlMaxAge))
+
+	w = s.preflight("/items/5", "https://app.example.com", "DELETE")
+	s.Equal(http.StatusNoContent, w.Code)
+	s.Equal("DELETE", w.Header().Get(headerAccessControlAllowMethods))
+}
+
+func (s *CORSTestSuite) TestPreflightUnknownPath() {
+	w := s.preflight("/missing", "https://app.example.com", "GET")
+	s.Equal(http.StatusNotFound, w.Code)
+	s.Empty(w.Header().Get(headerAccessControlAllowOrigin))
+}
+
+func (s *CORSTestSuite) TestRouteOverride() {
+	w := s.request("GET", "/public", "https://anyone.com")
+	s.Equal("*", w.Header().Get(headerAccessControlAllowOrigin))
+	s.Empty(w.Header().Get(headerAccessControlAllowCredentials))
+}
+
+func (s *CORSTestSuite) TestGroupInheritsPolicy() {
+	s.router.Group("/v2", func(g Router) {
+		g.Route("/items", func(c ctx.Context) {}).Methods("PUT")
+	})
+
+	w := s.preflight("/v2/items", "https://app.example.com", "PUT")
+	s.Equal(http.StatusNoContent, w.Code)
+	s.Equal("PUT", w.Header().Get(headerAccessControlAllowMethods))
+}
+
+func (s *CORSTestSuite) TestDefaultCORSPolicy() {
+	s.T().Setenv(CORSOriginsVariable, "https://app.example.com, https://*.example.org")
+	policy := DefaultCORSPolicy()
+	s.Equal([]string{"https://app.example.com", "https://*.example.org"}, policy.AllowedOrigins)
+	s.True(policy.AllowCredentials)
+
+	s.T().Setenv(CORSOriginsVariable, "")
+	_, ok := DefaultCORSPolicy().allowOrigin("https://evil.com")
+	s.False(ok)
+}
+
+func (s *CORSTestSuite) TestEnableCORSWarnsWithoutOrigins() {
+	s.T().Setenv(CORSOriginsVariable, "")
+	log := newRecordingLogger()
+	NewRouter(log, di.GetContainer()).EnableCORS()
+	s.Len(*log.warnings, 1)
+
+	s.T().Setenv(CORSOriginsVariable, "https://app.example.com")
+	log = newRecordingLogger()
+	NewRouter(log, di.GetContainer()).EnableCORS()
+	s.Empty(*log.warnings)
+}
+
+func (s *CORSTestSuite) TestPreflightRequestID() {
+	w := s.preflight("/items", "https://app.example.com", "POST")
+	s.Equal(http.StatusNoContent, w.Code)
+	s.True(ctx.ValidRequestID(w.Header().Get(ctx.HeaderRequestID)))
+
+	req := httptest.NewRequest("OPTIONS", "/items", nil)
+	req.Header.Set(headerOrigin, "https://app.example.com")
+	req.Header.Set(headerAccessControlRequestMethod, "POST")
+	req.Header.Set(ctx.HeaderRequestID, "client-id-1")
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	s.Equal("client-id-1", w.Header().Get(ctx.HeaderRequestID))
+}
@@ -0,0 +1,157 @@
+package router
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/kgrunwald/goweb/ctx"
+	"github.com/kgrunwald/goweb/ilog"
+)
+
+// APIGatewayEmulator implements the http.Handler interface by converting every request into an
+// events.APIGatewayProxyRequest, serving it through the Lambda path of the Router and converting the
+// events.APIGatewayProxyResponse back into an HTTP response. It allows an application to be run locally in
+// lambda-apigw-v1 mode without deploying it.
+type APIGatewayEmulator struct {
+	Router Router
+	Log    ilog.Logger
+
+	// Stage is reported in the request context of every event.
+	Stage string
+
+	// StageVariables are passed with every event.
+	StageVariables map[string]string
+}
+
+// NewAPIGatewayEmulator returns an emulator serving requests through the router with the given stage and stage variables.
+func NewAPIGatewayEmulator(router Router, log ilog.Logger, stage string, stageVars map[string]string) *APIGatewayEmulator {
+	return &APIGatewayEmulator{
+		Router:         router,
+		Log:            log,
+		Stage:          stage,
+		StageVariables: stageVars,
+	}
+}
+
+// ServeHTTP converts the request into a proxy event and writes the proxy response.
+func (e *APIGatewayEmulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	event, err := e.RequestToEvent(r)
+	if err != nil {
+		e.Log.WithField("error", err).Error("Could not convert request to proxy event")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lc := &lambdacontext.LambdaContext{AwsRequestID: event.RequestContext.RequestID}
+	out, err := e.Router.HandleLambda(lambdacontext.NewContext(r.Context(), lc), event)
+	resp, ok := out.(events.APIGatewayProxyResponse)
+	if err != nil || !ok {
+		e.Log.WithFields("error", err, "requestId", event.RequestContext.RequestID).Error("Lambda handler failed")
+		http.Error(w, "Internal server error", http.StatusBadGateway)
+		return
+	}
+
+	if err := writeProxyResponse(w, resp); err != nil {
+		e.Log.WithField("error", err).Error("Could not write proxy response")
+	}
+}
+
+// RequestToEvent builds the events.APIGatewayProxyRequest that API Gateway would send for the request, including
+// the request context and stage variables.
+func (e *APIGatewayEmulator) RequestToEvent(r *http.Request) (events.APIGatewayProxyRequest, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, err
+	}
+
+	event := events.APIGatewayProxyRequest{
+		Resource:                        "/{proxy+}",
+		Path:                            r.URL.Path,
+		HTTPMethod:                      r.Method,
+		Headers:                         map[string]string{},
+		MultiValueHeaders:               map[string][]string{},
+		QueryStringParameters:           map[string]string{},
+		MultiValueQueryStringParameters: map[string][]string{},
+		PathParameters:                  map[string]string{"proxy": strings.TrimPrefix(r.URL.Path, "/")},
+		StageVariables:                  e.StageVariables,
+	}
+
+	if utf8.Valid(body) {
+		event.Body = string(body)
+	} else {
+		event.Body = base64.StdEncoding.EncodeToString(body)
+		event.IsBase64Encoded = true
+	}
+
+	for k, v := range r.Header {
+		event.Headers[k] = v[len(v)-1]
+		event.MultiValueHeaders[k] = v
+	}
+	event.Headers["Host"] = r.Host
+	event.MultiValueHeaders["Host"] = []string{r.Host}
+
+	for k, v := range r.URL.Query() {
+		event.QueryStringParameters[k] = v[len(v)-1]
+		event.MultiValueQueryStringParameters[k] = v
+	}
+
+	sourceIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		sourceIP = host
+	}
+
+	now := time.Now()
+	event.RequestContext = events.APIGatewayProxyRequestContext{
+		AccountID:        "123456789012",
+		ResourceID:       "local",
+		Stage:            e.Stage,
+		DomainName:       r.Host,
+		DomainPrefix:     strings.Split(r.Host, ".")[0],
+		RequestID:        ctx.NewRequestID(),
+		Protocol:         r.Proto,
+		Identity:         events.APIGatewayRequestIdentity{SourceIP: sourceIP, UserAgent: r.UserAgent()},
+		ResourcePath:     "/{proxy+}",
+		HTTPMethod:       r.Method,
+		RequestTime:      now.UTC().Format("02/Jan/2006:15:04:05 -0700"),
+		RequestTimeEpoch: now.UnixNano() / int64(time.Millisecond),
+		APIID:            "local",
+	}
+
+	return event, nil
+}
+
+// writeProxyResponse writes the status, headers and decoded body of the proxy response.
+func writeProxyResponse(w http.ResponseWriter, resp events.APIGatewayProxyResponse) error {
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	for k, values := range resp.MultiValueHeaders {
+		w.Header().Del(k)
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return err
+		}
+		body = decoded
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(resp.StatusCode)
+	_, err := w.Write(body)
+	return err
+}
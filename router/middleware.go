@@ -1,21 +1,276 @@
 package router
 
-// func LogMiddleware(l ilog.Logger) Middleware {
-// 	return func(next http.Handler) http.Handler {
-// 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-// 			start := time.Now()
-
-// 			sw := &statusWriter{ResponseWriter: w}
-// 			next.ServeHTTP(sw, r)
-
-// 			duration := time.Now().Sub(start)
-// 			l.WithFields(
-// 				"Duration", duration,
-// 				"Status", sw.status,
-// 				"Method", r.Method,
-// 				"RequestURI", r.RequestURI,
-// 				"RequestId", sw.Header().Get("RequestID"),
-// 			).Info("Access log")
-// 		})
-// 	}
-// }
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kgrunwald/goweb/ctx"
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/pubsub"
+)
+
+const (
+	// AccessLogVariable is the name of the environment variable that enables the access log when set to `true`.
+	AccessLogVariable = "GO_API_ACCESS_LOG"
+
+	// AccessLogSampleRateVariable is the name of the environment variable that sets the fraction of requests, between 0
+	// and 1, written to the access log.
+	AccessLogSampleRateVariable = "GO_API_ACCESS_LOG_SAMPLE_RATE"
+
+	// AccessLogExcludeVariable is the name of the environment variable that contains a comma separated list of paths,
+	// such as `/health,/ready`, that are never written to the access log.
+	AccessLogExcludeVariable = "GO_API_ACCESS_LOG_EXCLUDE"
+)
+
+// AccessLogOptions configures AccessLogMiddleware.
+type AccessLogOptions struct {
+	// SampleRate is the fraction of requests that are logged. Responses with a 5xx status are always logged.
+	SampleRate float64
+
+	// Exclude lists path templates or request paths that are never logged, typically health checks.
+	Exclude []string
+}
+
+// DefaultAccessLogOptions returns the options configured by the AccessLogSampleRateVariable and
+// AccessLogExcludeVariable environment variables. The second return value reports whether AccessLogVariable enables the
+// access log.
+func DefaultAccessLogOptions() (AccessLogOptions, bool) {
+	options := AccessLogOptions{SampleRate: 1}
+
+	if rate, err := strconv.ParseFloat(os.Getenv(AccessLogSampleRateVariable), 64); err == nil && rate >= 0 && rate <= 1 {
+		options.SampleRate = rate
+	}
+
+	for _, p := range strings.Split(os.Getenv(AccessLogExcludeVariable), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			options.Exclude = append(options.Exclude, p)
+		}
+	}
+
+	return options, os.Getenv(AccessLogVariable) == "true"
+}
+
+// RequestIDMiddleware assigns the request ID at the edge of the application. A valid X-Request-ID request header is
+// reused, followed by the request ID of API Gateway and of the Lambda invocation, and otherwise a new ID is generated.
+// The ID is stored in the request context, where every ctx.Context and logger created for the request picks it up, and
+// returned to the client in the X-Request-ID response header.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = withRequestID(r)
+		w.Header().Set(ctx.HeaderRequestID, requestID(r))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withRequestID returns the request with a request ID in its context, keeping one that is already present.
+func withRequestID(r *http.Request) *http.Request {
+	if _, ok := ctx.RequestIDFromContext(r.Context()); ok {
+		return r
+	}
+
+	id := r.Header.Get(ctx.HeaderRequestID)
+	if !ctx.ValidRequestID(id) {
+		id = lambdaRequestID(r)
+	}
+	if id == "" {
+		id = ctx.NewRequestID()
+	}
+	return r.WithContext(ctx.WithRequestID(r.Context(), id))
+}
+
+// requestID returns the request ID stored in the request context, falling back to the ID assigned by Lambda for
+// requests that have not passed through RequestIDMiddleware.
+func requestID(r *http.Request) string {
+	if id, ok := ctx.RequestIDFromContext(r.Context()); ok {
+		return id
+	}
+	return lambdaRequestID(r)
+}
+
+// lambdaRequestID returns the ID API Gateway assigned to the request, or the ID of the Lambda invocation for event
+// sources without one such as Application Load Balancers.
+func lambdaRequestID(r *http.Request) string {
+	if apiGw, ok := GetAPIGatewayContextFromContext(r.Context()); ok && apiGw.RequestID != "" {
+		return apiGw.RequestID
+	}
+	if apiGw, ok := GetAPIGatewayV2ContextFromContext(r.Context()); ok && apiGw.RequestID != "" {
+		return apiGw.RequestID
+	}
+	if lc, ok := GetRuntimeContextFromContext(r.Context()); ok && lc != nil {
+		return lc.AwsRequestID
+	}
+	return ""
+}
+
+// PanicEvent is dispatched on the bus when RecoveryMiddleware recovers from a panic in a handler. Subscribe to it to
+// forward panics to an alerting service.
+type PanicEvent struct {
+	RequestID string
+	Method    string
+	Path      string
+	Value     interface{}
+	Stack     string
+}
+
+// RecoveryMiddleware recovers from panics in the handlers it wraps. The panic is logged with its stack trace and the
+// request ID, a PanicEvent is dispatched on the bus if it is not nil, and the client receives a 500 response encoded
+// in the negotiated format, which is a SOAP Fault for SOAP requests. If the handler had already started the response it
+// cannot be replaced, so the middleware panics with http.ErrAbortHandler to abort it instead of completing a truncated
+// response. http.ErrAbortHandler raised by the handler is passed on unchanged.
+func RecoveryMiddleware(l ilog.Logger, bus pubsub.Bus) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sw := &statusWriter{ResponseWriter: w}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+
+				event := PanicEvent{
+					RequestID: requestID(r),
+					Method:    r.Method,
+					Path:      r.URL.Path,
+					Value:     p,
+					Stack:     string(debug.Stack()),
+				}
+				l.WithFields(
+					"requestID", event.RequestID,
+					"method", event.Method,
+					"path", event.Path,
+					"panic", fmt.Sprint(p),
+					"stack", event.Stack,
+				).Error("Recovered from panic")
+
+				if bus != nil {
+					bus.Dispatch(event)
+				}
+
+				if sw.status != 0 {
+					panic(http.ErrAbortHandler)
+				}
+				ctx.New(r, w, l).Respond(http.StatusInternalServerError, &ctx.ErrorMessage{Message: "Internal server error"})
+			}()
+
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
+
+// matchedRoute receives the path template of the route matched for a request from recordMatchedRoute, so that
+// middleware running before routing can report it.
+type matchedRoute struct {
+	template string
+}
+
+type matchedRouteKey struct{}
+
+// recordMatchedRoute stores the path template of the matched route in the matchedRoute of the request, if it has one.
+func recordMatchedRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if matched, ok := r.Context().Value(matchedRouteKey{}).(*matchedRoute); ok {
+			matched.template = routeTemplate(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routeTemplate returns the path template of the route matched for the request, or an empty string before routing.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if t, err := route.GetPathTemplate(); err == nil {
+			return t
+		}
+	}
+	return ""
+}
+
+// AccessLogMiddleware logs one entry per request with the method, the path template of the matched route, the status,
+// the number of bytes written, the latency, the request ID, the remote IP and the user agent. Requests that match no
+// route are logged with their path. The root router runs it for every request when AccessLogVariable is set.
+func AccessLogMiddleware(l ilog.Logger, options AccessLogOptions) Middleware {
+	exclude := make(map[string]bool, len(options.Exclude))
+	for _, p := range options.Exclude {
+		exclude[p] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exclude[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			template := routeTemplate(r)
+			matched := &matchedRoute{}
+			if template == "" {
+				r = r.WithContext(context.WithValue(r.Context(), matchedRouteKey{}, matched))
+			}
+
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			if template == "" {
+				template = matched.template
+			}
+			if template == "" {
+				template = r.URL.Path
+			}
+			if exclude[template] {
+				return
+			}
+
+			if sw.status == 0 {
+				sw.status = http.StatusOK
+			}
+			if sw.status < http.StatusInternalServerError && rand.Float64() >= options.SampleRate {
+				return
+			}
+
+			l.WithFields(
+				"method", r.Method,
+				"path", template,
+				"status", sw.status,
+				"bytes", sw.length,
+				"latencyMs", float64(time.Since(start))/float64(time.Millisecond),
+				"requestID", requestID(r),
+				"remoteIP", remoteIP(r),
+				"userAgent", r.UserAgent(),
+			).Info("Access log")
+		})
+	}
+}
+
+// remoteIP returns the address of the client. Requests from API Gateway REST APIs carry it in the request context and
+// requests from an Application Load Balancer in the last entry of X-Forwarded-For.
+func remoteIP(r *http.Request) string {
+	if r.RemoteAddr != "" {
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			return host
+		}
+		return r.RemoteAddr
+	}
+
+	if apiGw, ok := GetAPIGatewayContextFromContext(r.Context()); ok && apiGw.Identity.SourceIP != "" {
+		return apiGw.Identity.SourceIP
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		return strings.TrimSpace(hops[len(hops)-1])
+	}
+	return ""
+}
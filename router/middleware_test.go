@@ -0,0 +1,291 @@
+package router
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kgrunwald/goweb/ctx"
+	"github.com/kgrunwald/goweb/di"
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/pubsub"
+	"github.com/stretchr/testify/suite"
+)
+
+// recordingLogger keeps the fields of every entry logged at info level and the message of every warning.
+type recordingLogger struct {
+	fields   map[string]interface{}
+	entries  *[]map[string]interface{}
+	warnings *[]string
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{fields: map[string]interface{}{}, entries: &[]map[string]interface{}{}, warnings: &[]string{}}
+}
+
+func (l *recordingLogger) Debug(string)    {}
+func (l *recordingLogger) Info(string)     { *l.entries = append(*l.entries, l.fields) }
+func (l *recordingLogger) Warn(msg string) { *l.warnings = append(*l.warnings, msg) }
+func (l *recordingLogger) Error(string)    {}
+func (l *recordingLogger) Fatal(string)    {}
+
+func (l *recordingLogger) WithField(key string, value interface{}) ilog.Logger {
+	return l.WithFields(key, value)
+}
+
+func (l *recordingLogger) WithFields(values ...interface{}) ilog.Logger {
+	fields := map[string]interface{}{}
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	for i := 0; i+1 < len(values); i += 2 {
+		fields[values[i].(string)] = values[i+1]
+	}
+	return &recordingLogger{fields: fields, entries: l.entries, warnings: l.warnings}
+}
+
+type MiddlewareTestSuite struct {
+	suite.Suite
+	router *muxRouter
+	log    *recordingLogger
+}
+
+func TestMiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(MiddlewareTestSuite))
+}
+
+func (s *MiddlewareTestSuite) SetupTest() {
+	s.log = newRecordingLogger()
+	s.router = NewRouter(ilog.NewLogger(), di.GetContainer()).(*muxRouter)
+	s.router.Route("/items/{id}", func(c ctx.Context, id int) (int, string, error) {
+		return http.StatusCreated, "created", nil
+	})
+	s.router.Route("/health", func(c ctx.Context) {})
+	s.router.Route("/fail", func(c ctx.Context) error {
+		return errors.New("failed")
+	})
+}
+
+func (s *MiddlewareTestSuite) serve(method, path string) {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", "test")
+	s.router.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func (s *MiddlewareTestSuite) TestAccessLog() {
+	s.router.Use(AccessLogMiddleware(s.log, AccessLogOptions{SampleRate: 1}))
+	s.serve("GET", "/items/5")
+
+	s.Require().Len(*s.log.entries, 1)
+	entry := (*s.log.entries)[0]
+	s.Equal("GET", entry["method"])
+	s.Equal("/items/{id}", entry["path"])
+	s.Equal(http.StatusCreated, entry["status"])
+	s.Equal(len(`"created"`)+1, entry["bytes"])
+	s.Equal("10.0.0.1", entry["remoteIP"])
+	s.Equal("test", entry["userAgent"])
+	s.NotEmpty(entry["requestID"])
+	s.Contains(entry, "latencyMs")
+}
+
+func (s *MiddlewareTestSuite) TestAccessLogUnmatched() {
+	s.router.useEdge(AccessLogMiddleware(s.log, AccessLogOptions{SampleRate: 1, Exclude: []string{"/health"}}))
+	s.serve("GET", "/missing")
+	s.serve("POST", "/items/5/other")
+	s.router.Route("/only-get", func(c ctx.Context) {}).Methods("GET")
+	s.serve("DELETE", "/only-get")
+	s.serve("GET", "/items/7")
+	s.serve("GET", "/health")
+
+	s.Require().Len(*s.log.entries, 4)
+	s.Equal(http.StatusNotFound, (*s.log.entries)[0]["status"])
+	s.Equal("/missing", (*s.log.entries)[0]["path"])
+	s.NotEmpty((*s.log.entries)[0]["requestID"])
+	s.Equal(http.StatusNotFound, (*s.log.entries)[1]["status"])
+	s.Equal(http.StatusMethodNotAllowed, (*s.log.entries)[2]["status"])
+	s.Equal("/only-get", (*s.log.entries)[2]["path"])
+	s.Equal(http.StatusCreated, (*s.log.entries)[3]["status"])
+	s.Equal("/items/{id}", (*s.log.entries)[3]["path"], "the edge reports the template of the matched route")
+}
+
+func (s *MiddlewareTestSuite) TestAccessLogExclude() {
+	s.router.Use(AccessLogMiddleware(s.log, AccessLogOptions{SampleRate: 1, Exclude: []string{"/health"}}))
+	s.serve("GET", "/health")
+	s.Empty(*s.log.entries)
+}
+
+func (s *MiddlewareTestSuite) TestAccessLogSampling() {
+	s.router.Use(AccessLogMiddleware(s.log, AccessLogOptions{SampleRate: 0}))
+	s.serve("GET", "/items/5")
+	s.Empty(*s.log.entries)
+
+	s.serve("GET", "/fail")
+	s.Require().Len(*s.log.entries, 1)
+	s.Equal(http.StatusInternalServerError, (*s.log.entries)[0]["status"])
+}
+
+func (s *MiddlewareTestSuite) TestDefaultAccessLogOptions() {
+	s.T().Setenv(AccessLogVariable, "true")
+	s.T().Setenv(AccessLogSampleRateVariable, "0.25")
+	s.T().Setenv(AccessLogExcludeVariable, "/health, /ready")
+
+	options, ok := DefaultAccessLogOptions()
+	s.True(ok)
+	s.Equal(0.25, options.SampleRate)
+	s.Equal([]string{"/health", "/ready"}, options.Exclude)
+}
+
+func (s *MiddlewareTestSuite) TestRequestID() {
+	var ids []string
+	s.router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ids = append(ids, ctx.New(r, w, s.log).RequestID())
+			next.ServeHTTP(w, r)
+		})
+	})
+	s.router.Route("/id", func(c ctx.Context) (string, error) {
+		return c.RequestID(), nil
+	})
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "/id", nil))
+	id := w.Header().Get(ctx.HeaderRequestID)
+	s.NotEmpty(id)
+	s.Equal([]string{id}, ids)
+	s.Equal(`"`+id+`"`+"\n", w.Body.String())
+
+	req := httptest.NewRequest("GET", "/id", nil)
+	req.Header.Set(ctx.HeaderRequestID, "client-id")
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	s.Equal("client-id", w.Header().Get(ctx.HeaderRequestID))
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	s.Equal(http.StatusNotFound, w.Code)
+	s.NotEmpty(w.Header().Get(ctx.HeaderRequestID))
+}
+
+func (s *MiddlewareTestSuite) TestRecovery() {
+	bus := pubsub.NewBus(ilog.NewLogger())
+	events := make(chan PanicEvent, 1)
+	bus.Subscribe(func(e PanicEvent) {
+		events <- e
+	})
+
+	s.router.Use(RecoveryMiddleware(s.log, bus))
+	s.router.Route("/panic", func(c ctx.Context) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest("GET", "/panic", nil)
+	req.Header.Set(ctx.HeaderRequestID, "panic-id")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.JSONEq(`{"error": "Internal server error"}`, w.Body.String())
+
+	select {
+	case e := <-events:
+		s.Equal("panic-id", e.RequestID)
+		s.Equal("/panic", e.Path)
+		s.Equal("boom", e.Value)
+		s.Contains(e.Stack, "runtime/debug.Stack")
+	case <-time.After(time.Second):
+		s.Fail("PanicEvent was not dispatched")
+	}
+}
+
+func (s *MiddlewareTestSuite) TestRecoverySOAP() {
+	s.router.Route("/panic", func(c ctx.Context) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest("POST", "/panic", nil)
+	req.Header.Set(ctx.HeaderContentType, ctx.ContentTypeTextXML)
+	req.Header.Set(ctx.HeaderSOAPAction, "panic")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.Contains(w.Body.String(), "Fault")
+	s.Contains(w.Body.String(), "Internal server error")
+}
+
+func (s *MiddlewareTestSuite) TestRecoveryNotFoundHandler() {
+	s.router.NotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+	s.Equal(http.StatusInternalServerError, w.Code)
+	s.JSONEq(`{"error": "Internal server error"}`, w.Body.String())
+}
+
+func (s *MiddlewareTestSuite) TestHijack() {
+	s.router.Route("/upgrade", func(c ctx.Context) {
+		hijacker, ok := c.Writer().(http.Hijacker)
+		if !ok {
+			c.Writer().WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		conn, rw, err := hijacker.Hijack()
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\nhello")
+		rw.Flush()
+	})
+
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	s.Require().NoError(err)
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /upgrade HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	s.Require().NoError(err)
+	s.Equal(http.StatusSwitchingProtocols, resp.StatusCode)
+	data, _ := ioutil.ReadAll(br)
+	s.Equal("hello", string(data))
+}
+
+// pushRecorder is a ResponseRecorder that supports HTTP/2 server push.
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func (s *MiddlewareTestSuite) TestPush() {
+	s.router.Route("/page", func(c ctx.Context) error {
+		pusher, ok := c.Writer().(http.Pusher)
+		s.Require().True(ok)
+		return pusher.Push("/app.js", nil)
+	})
+
+	w := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "/page", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal([]string{"/app.js"}, w.pushed)
+
+	sw := &statusWriter{ResponseWriter: httptest.NewRecorder()}
+	s.Equal(http.ErrNotSupported, sw.Push("/app.js", nil))
+	_, _, err := sw.Hijack()
+	s.Error(err)
+}
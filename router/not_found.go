@@ -0,0 +1,32 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kgrunwald/goweb/ctx"
+)
+
+// notFoundHandler answers requests for paths without a route with a 404 response encoded in the negotiated format.
+func (r *muxRouter) notFoundHandler(w http.ResponseWriter, req *http.Request) {
+	ctx.New(req, w, r.logger).NotFound(&ctx.ErrorMessage{Message: "Not found"})
+}
+
+// methodNotAllowedHandler answers requests for a path whose routes do not accept the method with a 405 response
+// encoded in the negotiated format.
+func (r *muxRouter) methodNotAllowedHandler(w http.ResponseWriter, req *http.Request) {
+	ctx.New(req, w, r.logger).Respond(http.StatusMethodNotAllowed, &ctx.ErrorMessage{Message: "Method not allowed"})
+}
+
+func (r *muxRouter) NotFound(handler http.Handler) Router {
+	r.mux.NotFoundHandler = handler
+	return r
+}
+
+func (r *muxRouter) MethodNotAllowed(handler http.Handler) Router {
+	r.mux.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Allow", strings.Join(r.allowedMethods(req), ", "))
+		handler.ServeHTTP(w, req)
+	})
+	return r
+}
@@ -0,0 +1,244 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/kgrunwald/goweb/ctx"
+	"github.com/kgrunwald/goweb/openapi"
+)
+
+// Environment variables read by NewRouter. When OpenAPIPathVariable is set the OpenAPI document of the application is
+// served at that path, see Router.ServeOpenAPI. The title and version of the document default to `API` and `1.0.0`.
+const (
+	OpenAPIPathVariable    = "GO_API_OPENAPI_PATH"
+	OpenAPITitleVariable   = "GO_API_OPENAPI_TITLE"
+	OpenAPIVersionVariable = "GO_API_OPENAPI_VERSION"
+)
+
+// bodyContentTypes are the content types documented for request and response bodies.
+var bodyContentTypes = []string{"application/json", "application/xml"}
+
+// SecurityScheme is an authentication scheme that can protect routes, such as auth.APIKeyScheme and auth.JWTScheme.
+// The middleware rejects unauthenticated requests and the scheme describes itself for the OpenAPI document.
+type SecurityScheme interface {
+	Middleware(next http.Handler) http.Handler
+
+	// OpenAPISecurityScheme returns the name of the scheme in the document along with its description.
+	OpenAPISecurityScheme() (string, openapi.SecurityScheme)
+}
+
+// DefaultOpenAPIInfo returns the document info read from OpenAPITitleVariable and OpenAPIVersionVariable.
+func DefaultOpenAPIInfo() openapi.Info {
+	info := openapi.Info{Title: os.Getenv(OpenAPITitleVariable), Version: os.Getenv(OpenAPIVersionVariable)}
+	if info.Title == "" {
+		info.Title = "API"
+	}
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+	return info
+}
+
+func (r *muxRoute) Secure(schemes ...SecurityScheme) Route {
+	for _, scheme := range schemes {
+		r.Use(scheme.Middleware)
+	}
+	r.security = append(r.security, schemes...)
+	return r
+}
+
+func (r *muxRouter) Secure(schemes ...SecurityScheme) Router {
+	for _, scheme := range schemes {
+		r.Use(scheme.Middleware)
+	}
+	r.security = append(r.security, schemes...)
+	return r
+}
+
+// securitySchemes returns the schemes protecting the route, starting with those of the outermost router.
+func (r *muxRoute) securitySchemes() []SecurityScheme {
+	var schemes []SecurityScheme
+	for router := r.router; router != nil; router = router.parent {
+		schemes = append(append([]SecurityScheme{}, router.security...), schemes...)
+	}
+	return append(schemes, r.security...)
+}
+
+func (r *muxRouter) OpenAPI(info openapi.Info) *openapi.Document {
+	doc := openapi.New(info)
+	if len(r.stripBasePath) > 1 {
+		doc.Servers = []openapi.Server{{URL: r.stripBasePath}}
+	}
+
+	r.mux.Walk(func(mr *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route, ok := r.registry.routes[mr]; ok && route.target != nil {
+			route.document(doc)
+		}
+		return nil
+	})
+	return doc
+}
+
+func (r *muxRouter) ServeOpenAPI(path string, info openapi.Info) Route {
+	route := &muxRoute{route: r.mux.NewRoute(), router: r, namePrefix: r.namePrefix}
+	route.Path(path).Methods(http.MethodGet).Handler(func(w http.ResponseWriter, req *http.Request) {
+		doc := r.OpenAPI(info)
+		doc.Servers = []openapi.Server{{URL: baseURL(req) + r.stripBasePath}}
+
+		w.Header().Set(contentTypeHeaderKey, "application/json")
+		if err := json.NewEncoder(w).Encode(doc); err != nil {
+			r.logger.WithField("error", err).Error("Could not encode OpenAPI document")
+		}
+	})
+	r.registry.routes[route.route] = route
+	return route
+}
+
+// document adds the operations of the route to the document. Routes that do not restrict their methods are documented
+// for every method but HEAD. When several routes share a path and method, differing only in their header matchers,
+// the first one registered is documented.
+func (r *muxRoute) document(doc *openapi.Document) {
+	template, vars := openAPIPath(r.GetPath())
+	methods, err := r.route.GetMethods()
+	if err != nil {
+		for _, method := range routeMethods {
+			if method != http.MethodHead {
+				methods = append(methods, method)
+			}
+		}
+	}
+
+	item, ok := doc.Paths[template]
+	if !ok {
+		item = openapi.PathItem{}
+		doc.Paths[template] = item
+	}
+	for _, method := range methods {
+		method = strings.ToLower(method)
+		if _, exists := item[method]; !exists {
+			item[method] = r.operation(doc, vars)
+		}
+	}
+}
+
+// operation describes the handler of the route. Path variables, parameter structs and the body struct are taken from
+// the arguments of the controller method and the responses from its return values, see RouteHandler.Handle.
+func (r *muxRoute) operation(doc *openapi.Document, patterns map[string]string) *openapi.Operation {
+	op := &openapi.Operation{OperationID: r.route.GetName(), Responses: map[string]*openapi.Response{}}
+	method := r.target.Method.Type()
+	vars := r.target.Binding.Vars
+
+	badRequest := reflect.TypeOf(ctx.ErrorMessage{})
+	for idx := 1; idx < method.NumIn(); idx++ {
+		argType := method.In(idx)
+		switch classifyParam(method, idx, len(vars), r.target.Container) {
+		case paramPath:
+			schema := parameterSchema(doc, argType)
+			if schema.Type == "string" {
+				schema.Pattern = patterns[vars[idx-1]]
+			}
+			op.Parameters = append(op.Parameters, openapi.Parameter{Name: vars[idx-1], In: "path", Required: true, Schema: schema})
+		case paramStruct:
+			op.Parameters = append(op.Parameters, structParameters(doc, argType)...)
+			badRequest = reflect.TypeOf(BindErrorResponse{})
+		case paramBody:
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: content(doc.Schema(argType))}
+		}
+	}
+
+	if len(op.Parameters) > 0 || op.RequestBody != nil {
+		op.Responses["400"] = &openapi.Response{Description: "Bad request", Content: content(doc.Schema(badRequest))}
+	}
+
+	// Requests not matching the header matchers are routed elsewhere, so they are required but never cause a 400.
+	for i := 0; i < len(r.headers)-1; i += 2 {
+		schema := &openapi.Schema{Type: "string"}
+		if r.headers[i+1] != "" {
+			schema.Enum = []string{r.headers[i+1]}
+		}
+		op.Parameters = append(op.Parameters, openapi.Parameter{Name: r.headers[i], In: "header", Required: true, Schema: schema})
+	}
+
+	switch method.NumOut() {
+	case 0, 1:
+		op.Responses["200"] = &openapi.Response{Description: "OK"}
+	case 2:
+		op.Responses["200"] = &openapi.Response{Description: "OK", Content: content(doc.Schema(method.Out(0)))}
+	case 3:
+		op.Responses["default"] = &openapi.Response{
+			Description: "Response with the status code returned by the handler",
+			Content:     content(doc.Schema(method.Out(1))),
+		}
+	}
+
+	errorMessage := content(doc.Schema(reflect.TypeOf(ctx.ErrorMessage{})))
+	if schemes := r.securitySchemes(); len(schemes) > 0 {
+		requirement := openapi.SecurityRequirement{}
+		for _, scheme := range schemes {
+			name, description := scheme.OpenAPISecurityScheme()
+			doc.Components.SecuritySchemes[name] = &description
+			requirement[name] = []string{}
+		}
+		op.Security = []openapi.SecurityRequirement{requirement}
+		op.Responses["403"] = &openapi.Response{Description: "Forbidden", Content: errorMessage}
+	}
+	op.Responses["500"] = &openapi.Response{Description: "Internal server error", Content: errorMessage}
+	return op
+}
+
+// structParameters describes the tagged fields of a parameter struct.
+func structParameters(doc *openapi.Document, t reflect.Type) []openapi.Parameter {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var params []openapi.Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		source, name, ok := paramTag(field)
+		if !ok || field.PkgPath != "" {
+			continue
+		}
+		params = append(params, openapi.Parameter{Name: name, In: source, Schema: parameterSchema(doc, field.Type)})
+	}
+	return params
+}
+
+// parameterSchema describes a type converted from a request parameter. Types with a custom Converter are strings and
+// slices hold one element per repeated value, mirroring convertValues.
+func parameterSchema(doc *openapi.Document, t reflect.Type) *openapi.Schema {
+	if _, ok := getConverter(t); ok {
+		return &openapi.Schema{Type: "string"}
+	}
+	if t.Kind() == reflect.Slice {
+		return &openapi.Schema{Type: "array", Items: parameterSchema(doc, t.Elem())}
+	}
+	return doc.ParameterSchema(t)
+}
+
+func content(schema *openapi.Schema) map[string]openapi.MediaType {
+	out := map[string]openapi.MediaType{}
+	for _, contentType := range bodyContentTypes {
+		out[contentType] = openapi.MediaType{Schema: schema}
+	}
+	return out
+}
+
+// openAPIPath removes the regular expressions from the variables of a route template, turning `/items/{id:[0-9]+}`
+// into `/items/{id}`. The expressions are returned by variable name.
+func openAPIPath(template string) (string, map[string]string) {
+	patterns := map[string]string{}
+	path := pathVarPattern.ReplaceAllStringFunc(template, func(match string) string {
+		parts := strings.SplitN(match[1:len(match)-1], ":", 2)
+		if len(parts) == 2 {
+			patterns[parts[0]] = "^" + parts[1] + "$"
+		}
+		return "{" + parts[0] + "}"
+	})
+	return path, patterns
+}
@@ -0,0 +1,150 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kgrunwald/goweb/ctx"
+	"github.com/kgrunwald/goweb/di"
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/openapi"
+	"github.com/stretchr/testify/suite"
+)
+
+type OpenAPITestSuite struct {
+	suite.Suite
+	router Router
+}
+
+func TestOpenAPITestSuite(t *testing.T) {
+	suite.Run(t, new(OpenAPITestSuite))
+}
+
+func (s *OpenAPITestSuite) SetupTest() {
+	s.router = NewRouter(ilog.NewLogger(), di.GetContainer())
+}
+
+type item struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Parent  *item     `json:"parent,omitempty"`
+	secret  string
+}
+
+type headerScheme struct{}
+
+func (headerScheme) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Key") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (headerScheme) OpenAPISecurityScheme() (string, openapi.SecurityScheme) {
+	return "key", openapi.SecurityScheme{Type: "apiKey", In: "header", Name: "X-Key"}
+}
+
+func (s *OpenAPITestSuite) TestOperations() {
+	s.router.Route("/items/{id:[0-9]+}", func(c ctx.Context, id int) (*item, error) {
+		return nil, nil
+	}).Methods("GET").Name("items.get")
+	s.router.Route("/items", func(c ctx.Context, params listParams) ([]item, error) {
+		return nil, nil
+	}).Methods("GET")
+	s.router.Route("/items", func(c ctx.Context, body *item) (int, *item, error) {
+		return http.StatusCreated, body, nil
+	}).Methods("POST")
+	s.router.Route("/items/{slug:[a-z]+}/tags", func(c ctx.Context, slug string) {}).Headers("X-Version", "2")
+
+	doc := s.router.OpenAPI(openapi.Info{Title: "Items", Version: "1.0.0"})
+	s.Equal(openapi.Version, doc.OpenAPI)
+
+	get := doc.Paths["/items/{id}"]["get"]
+	s.Equal("items.get", get.OperationID)
+	s.Equal([]openapi.Parameter{{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "integer", Format: "int64"}}}, get.Parameters)
+	s.Equal(openapi.Ref("item"), get.Responses["200"].Content["application/json"].Schema)
+	s.Equal(openapi.Ref("ErrorMessage"), get.Responses["400"].Content["application/json"].Schema)
+	s.Contains(get.Responses, "500")
+
+	list := doc.Paths["/items"]["get"]
+	s.Equal([]openapi.Parameter{
+		{Name: "page", In: "query", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+		{Name: "tag", In: "query", Schema: &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}}},
+		{Name: "X-Tenant", In: "header", Schema: &openapi.Schema{Type: "string"}},
+		{Name: "session", In: "cookie", Schema: &openapi.Schema{Type: "string"}},
+	}, list.Parameters)
+	s.Equal(&openapi.Schema{Type: "array", Items: openapi.Ref("item")}, list.Responses["200"].Content["application/json"].Schema)
+	s.Equal(openapi.Ref("BindErrorResponse"), list.Responses["400"].Content["application/json"].Schema)
+
+	post := doc.Paths["/items"]["post"]
+	s.Equal(openapi.Ref("item"), post.RequestBody.Content["application/xml"].Schema)
+	s.True(post.RequestBody.Required)
+	s.Equal(openapi.Ref("item"), post.Responses["default"].Content["application/json"].Schema)
+
+	tags := doc.Paths["/items/{slug}/tags"]
+	s.Len(tags, 5)
+	s.Equal(&openapi.Schema{Type: "string", Pattern: "^[a-z]+$"}, tags["put"].Parameters[0].Schema)
+	s.Equal(openapi.Parameter{Name: "X-Version", In: "header", Required: true, Schema: &openapi.Schema{Type: "string", Enum: []string{"2"}}}, tags["put"].Parameters[1])
+	s.Equal(&openapi.Response{Description: "OK"}, tags["delete"].Responses["200"])
+
+	s.Equal(&openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+		"id":      {Type: "integer", Format: "int64"},
+		"name":    {Type: "string"},
+		"created": {Type: "string", Format: "date-time"},
+		"parent":  openapi.Ref("item"),
+	}}, doc.Components.Schemas["item"])
+}
+
+func (s *OpenAPITestSuite) TestSecurity() {
+	s.router.Group("/admin", func(g Router) {
+		g.Secure(headerScheme{})
+		g.Route("/stats", func(c ctx.Context) {}).Methods("GET")
+	})
+	s.router.Route("/public", func(c ctx.Context) {}).Methods("GET")
+
+	doc := s.router.OpenAPI(openapi.Info{})
+	s.Equal(&openapi.SecurityScheme{Type: "apiKey", In: "header", Name: "X-Key"}, doc.Components.SecuritySchemes["key"])
+	s.Equal([]openapi.SecurityRequirement{{"key": {}}}, doc.Paths["/admin/stats"]["get"].Security)
+	s.Contains(doc.Paths["/admin/stats"]["get"].Responses, "403")
+	s.Empty(doc.Paths["/public"]["get"].Security)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/stats", nil))
+	s.Equal(http.StatusForbidden, w.Code)
+}
+
+func (s *OpenAPITestSuite) TestServeOpenAPI() {
+	s.router.ServeOpenAPI("/openapi.json", openapi.Info{Title: "Items", Version: "2.0.0"})
+	s.router.Route("/items", func(c ctx.Context) {}).Methods("GET")
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "http://api.example.com/openapi.json", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("application/json", w.Header().Get(contentTypeHeaderKey))
+
+	doc := openapi.Document{}
+	s.NoError(json.Unmarshal(w.Body.Bytes(), &doc))
+	s.Equal("Items", doc.Info.Title)
+	s.Equal([]openapi.Server{{URL: "http://api.example.com"}}, doc.Servers)
+	s.Contains(doc.Paths, "/items")
+	s.NotContains(doc.Paths, "/openapi.json")
+}
+
+func (s *OpenAPITestSuite) TestServeOpenAPIFromEnvironment() {
+	s.T().Setenv(OpenAPIPathVariable, "/docs/openapi.json")
+	s.T().Setenv(OpenAPITitleVariable, "Env API")
+	r := NewRouter(ilog.NewLogger(), di.GetContainer())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/docs/openapi.json", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), `"title":"Env API"`)
+	s.Contains(w.Body.String(), `"version":"1.0.0"`)
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/kgrunwald/goweb/ctx"
+)
+
+// WithLambdaDeadline returns a copy of parent whose deadline is margin before the deadline of the Lambda invocation, so
+// that handlers watching Done stop in time for a response to be returned. The Lambda runtime sets the deadline of the
+// invocation context from the remaining time of the invocation. If parent has no deadline, as with requests from the
+// local emulator, only a cancel function is added.
+func WithLambdaDeadline(parent context.Context, margin time.Duration) (context.Context, context.CancelFunc) {
+	deadline, ok := parent.Deadline()
+	if !ok {
+		return context.WithCancel(parent)
+	}
+	return context.WithDeadline(parent, deadline.Add(-margin))
+}
+
+// serveProxy serves the request with a deadline derived from the Lambda invocation. If the handler has not returned
+// when the deadline passes, a 504 Gateway Timeout response is returned in its place and anything the handler writes
+// afterwards is discarded. Panics in the handler are raised again on the calling goroutine.
+func (r *muxRouter) serveProxy(req *http.Request) *ProxyResponseWriter {
+	c, cancel := WithLambdaDeadline(req.Context(), r.proxyOptions.DeadlineMargin)
+	defer cancel()
+	req = withRequestID(req.WithContext(c))
+
+	w := NewProxyResponseWriterWithOptions(r.proxyOptions, req.Header.Get("Accept-Encoding"))
+	if _, ok := c.Deadline(); !ok {
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	done := make(chan struct{})
+	panics := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panics <- p
+			}
+		}()
+		r.ServeHTTP(w, req)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return w
+	case p := <-panics:
+		panic(p)
+	case <-c.Done():
+		select {
+		case <-done:
+			return w
+		default:
+		}
+	}
+
+	r.logger.WithFields("method", req.Method, "path", req.URL.Path, "requestID", requestID(req)).Error("Request did not complete before the Lambda deadline")
+	timeout := NewProxyResponseWriterWithOptions(r.proxyOptions, req.Header.Get("Accept-Encoding"))
+	ctx.New(req, timeout, r.logger).Respond(http.StatusGatewayTimeout, &ctx.ErrorMessage{Message: "Request timed out"})
+	return timeout
+}
@@ -0,0 +1,174 @@
+package router
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	// BinaryMediaTypesVariable is the name of the environment variable that contains a comma separated list of media
+	// types, such as `image/*,application/pdf`, whose responses are always base64 encoded.
+	BinaryMediaTypesVariable = "GO_API_BINARY_MEDIA_TYPES"
+
+	// CompressVariable is the name of the environment variable that enables response compression when set to `true`.
+	// REST APIs must list the compressed media types, or `*/*`, as binary media types in API Gateway for the base64
+	// encoded body to be decoded before it is sent to the client.
+	CompressVariable = "GO_API_COMPRESS"
+
+	// DeadlineMarginVariable is the name of the environment variable that overrides DefaultDeadlineMargin, formatted as a
+	// Go duration such as `750ms`.
+	DeadlineMarginVariable = "GO_API_DEADLINE_MARGIN"
+
+	// DefaultDeadlineMargin is subtracted from the remaining time of the Lambda invocation to leave room for returning a
+	// 504 response before the platform ends the invocation.
+	DefaultDeadlineMargin = 500 * time.Millisecond
+
+	// MaxLambdaPayloadSize is the largest response body API Gateway and Lambda Function URLs accept from a function.
+	MaxLambdaPayloadSize = 6 * 1024 * 1024
+
+	// MaxALBPayloadSize is the largest response body an Application Load Balancer accepts from a function.
+	MaxALBPayloadSize = 1024 * 1024
+
+	// DefaultCompressMinSize is the smallest body that is compressed by default.
+	DefaultCompressMinSize = 1024
+
+	contentEncodingHeaderKey = "Content-Encoding"
+)
+
+// ProxyOptions configures how a ProxyResponseWriter encodes the body of a response for AWS Lambda.
+type ProxyOptions struct {
+	// BinaryMediaTypes lists media types that are always base64 encoded. A trailing `/*` matches every subtype.
+	BinaryMediaTypes []string
+
+	// Compress enables gzip or brotli encoding of text responses, chosen from the Accept-Encoding request header.
+	Compress bool
+
+	// CompressMinSize is the smallest body in bytes that will be compressed.
+	CompressMinSize int
+
+	// MaxPayloadSize overrides the maximum size of the encoded body. When it is 0 the limit of the event source is used.
+	MaxPayloadSize int
+
+	// DeadlineMargin is subtracted from the deadline of the Lambda invocation to derive the deadline of the request.
+	DeadlineMargin time.Duration
+}
+
+// DefaultProxyOptions returns the options configured by the BinaryMediaTypesVariable, CompressVariable and
+// DeadlineMarginVariable environment variables.
+func DefaultProxyOptions() ProxyOptions {
+	options := ProxyOptions{
+		Compress:        os.Getenv(CompressVariable) == "true",
+		CompressMinSize: DefaultCompressMinSize,
+		DeadlineMargin:  DefaultDeadlineMargin,
+	}
+
+	if margin, err := time.ParseDuration(os.Getenv(DeadlineMarginVariable)); err == nil && margin >= 0 {
+		options.DeadlineMargin = margin
+	}
+
+	for _, t := range strings.Split(os.Getenv(BinaryMediaTypesVariable), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			options.BinaryMediaTypes = append(options.BinaryMediaTypes, t)
+		}
+	}
+	return options
+}
+
+// PayloadTooLargeError is returned when an encoded response body exceeds the limit of the event source.
+type PayloadTooLargeError struct {
+	Size  int
+	Limit int
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("Response body of %d bytes exceeds the maximum Lambda response size of %d bytes", e.Size, e.Limit)
+}
+
+// isBinary reports whether the content type matches one of the configured binary media types.
+func (o ProxyOptions) isBinary(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+
+	for _, t := range o.BinaryMediaTypes {
+		if t == "*/*" || t == mediaType {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCompressible reports whether a body of the content type benefits from compression. Media that is usually already
+// compressed, such as images and archives, is skipped.
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.Contains(mediaType, "json") ||
+		strings.Contains(mediaType, "xml") ||
+		strings.Contains(mediaType, "javascript")
+}
+
+// negotiateEncoding picks brotli or gzip from an Accept-Encoding header, preferring brotli. An empty string is returned
+// if neither is accepted.
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		rejected := false
+		for _, param := range fields[1:] {
+			if q := strings.ReplaceAll(strings.TrimSpace(param), " ", ""); q == "q=0" || q == "q=0.0" || q == "q=0.00" || q == "q=0.000" {
+				rejected = true
+			}
+		}
+		if !rejected {
+			accepted[name] = true
+		}
+	}
+
+	switch {
+	case accepted["br"]:
+		return "br"
+	case accepted["gzip"]:
+		return "gzip"
+	}
+	return ""
+}
+
+// compress encodes the body with the given content encoding.
+func compress(encoding string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("Unsupported content encoding %s", encoding)
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -137,20 +137,7 @@ func (r *RequestAccessor) EventToRequest(req events.APIGatewayProxyRequest) (*ht
 		decodedBody = base64Body
 	}
 
-	path := req.Path
-	if r.stripBasePath != "" && len(r.stripBasePath) > 1 {
-		if strings.HasPrefix(path, r.stripBasePath) {
-			path = strings.Replace(path, r.stripBasePath, "", 1)
-		}
-	}
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
-	}
-	serverAddress := "https://" + req.RequestContext.DomainName
-	if customAddress, ok := os.LookupEnv(CustomHostVariable); ok {
-		serverAddress = customAddress
-	}
-	path = serverAddress + path
+	path := r.requestURL(req.Path, req.RequestContext.DomainName)
 
 	if len(req.MultiValueQueryStringParameters) > 0 {
 		queryString := ""
@@ -205,6 +192,28 @@ func (r *RequestAccessor) EventToRequest(req events.APIGatewayProxyRequest) (*ht
 	return httpRequest, nil
 }
 
+// requestURL builds the absolute URL for an event path, removing the base path configured with StripBasePath. The host
+// is taken from the CustomHostVariable environment variable if it is set, otherwise from the domain name of the event.
+func (r *RequestAccessor) requestURL(path, domainName string) string {
+	return r.requestURLWithScheme("https", path, domainName)
+}
+
+func (r *RequestAccessor) requestURLWithScheme(scheme, path, domainName string) string {
+	if r.stripBasePath != "" && len(r.stripBasePath) > 1 {
+		if strings.HasPrefix(path, r.stripBasePath) {
+			path = strings.Replace(path, r.stripBasePath, "", 1)
+		}
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	serverAddress := scheme + "://" + domainName
+	if customAddress, ok := os.LookupEnv(CustomHostVariable); ok {
+		serverAddress = customAddress
+	}
+	return serverAddress + path
+}
+
 func addToHeader(req *http.Request, apiGwRequest events.APIGatewayProxyRequest) (*http.Request, error) {
 	stageVars, err := json.Marshal(apiGwRequest.StageVariables)
 	if err != nil {
@@ -223,15 +232,16 @@ func addToHeader(req *http.Request, apiGwRequest events.APIGatewayProxyRequest)
 
 func addToContext(ctx context.Context, req *http.Request, apiGwRequest events.APIGatewayProxyRequest) *http.Request {
 	lc, _ := lambdacontext.FromContext(ctx)
-	rc := requestContext{lambdaContext: lc, gatewayProxyContext: apiGwRequest.RequestContext, stageVars: apiGwRequest.StageVariables}
+	rc := requestContext{source: sourceAPIGatewayV1, lambdaContext: lc, gatewayProxyContext: apiGwRequest.RequestContext, stageVars: apiGwRequest.StageVariables}
 	ctx = context.WithValue(ctx, ctxKey{}, rc)
 	return req.WithContext(ctx)
 }
 
-// GetAPIGatewayContextFromContext retrieve APIGatewayProxyRequestContext from context.Context
+// GetAPIGatewayContextFromContext retrieve APIGatewayProxyRequestContext from context.Context. It reports false unless
+// the request was created from an events.APIGatewayProxyRequest.
 func GetAPIGatewayContextFromContext(ctx context.Context) (events.APIGatewayProxyRequestContext, bool) {
 	v, ok := ctx.Value(ctxKey{}).(requestContext)
-	return v.gatewayProxyContext, ok
+	return v.gatewayProxyContext, ok && v.source == sourceAPIGatewayV1
 }
 
 // GetRuntimeContextFromContext retrieve Lambda Runtime Context from context.Context
@@ -240,16 +250,29 @@ func GetRuntimeContextFromContext(ctx context.Context) (*lambdacontext.LambdaCon
 	return v.lambdaContext, ok
 }
 
-// GetStageVarsFromContext retrieve stage variables from context
+// GetStageVarsFromContext retrieve stage variables from context. It reports false for requests that did not come
+// through API Gateway.
 func GetStageVarsFromContext(ctx context.Context) (map[string]string, bool) {
 	v, ok := ctx.Value(ctxKey{}).(requestContext)
-	return v.stageVars, ok
+	return v.stageVars, ok && v.source != sourceALB
 }
 
 type ctxKey struct{}
 
+// eventSource identifies the type of Lambda event a request was created from.
+type eventSource int
+
+const (
+	sourceAPIGatewayV1 eventSource = iota + 1
+	sourceAPIGatewayV2
+	sourceALB
+)
+
 type requestContext struct {
-	lambdaContext       *lambdacontext.LambdaContext
-	gatewayProxyContext events.APIGatewayProxyRequestContext
-	stageVars           map[string]string
+	source                eventSource
+	lambdaContext         *lambdacontext.LambdaContext
+	gatewayProxyContext   events.APIGatewayProxyRequestContext
+	gatewayV2ProxyContext events.APIGatewayV2HTTPRequestContext
+	albContext            events.ALBTargetGroupRequestContext
+	stageVars             map[string]string
 }
@@ -0,0 +1,114 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// EventToRequestALBWithContext converts an Application Load Balancer target group event and context into an http.Request object.
+// Returns the populated http request with lambda context and ALBTargetGroupRequestContext as part of its context.
+// Access those using GetALBContextFromContext and GetRuntimeContextFromContext functions in this package.
+func (r *RequestAccessor) EventToRequestALBWithContext(ctx context.Context, req events.ALBTargetGroupRequest) (*http.Request, error) {
+	httpRequest, err := r.EventToRequestALB(req)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return addALBToContext(ctx, httpRequest, req), nil
+}
+
+// EventToRequestALB converts an Application Load Balancer target group event into an http.Request object. Both the
+// single value and the multi value header modes of the target group are supported.
+// Returns the populated request maintaining headers
+func (r *RequestAccessor) EventToRequestALB(req events.ALBTargetGroupRequest) (*http.Request, error) {
+	decodedBody := []byte(req.Body)
+	if req.IsBase64Encoded {
+		base64Body, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		decodedBody = base64Body
+	}
+
+	headers := make(http.Header)
+	if req.MultiValueHeaders != nil {
+		for k, values := range req.MultiValueHeaders {
+			for _, value := range values {
+				headers.Add(k, value)
+			}
+		}
+	} else {
+		for h := range req.Headers {
+			headers.Add(h, req.Headers[h])
+		}
+	}
+
+	scheme := headers.Get("X-Forwarded-Proto")
+	if scheme == "" {
+		scheme = "https"
+	}
+	path := r.requestURLWithScheme(scheme, req.Path, headers.Get("Host"))
+
+	// The load balancer passes query string parameters through exactly as the client sent them, so they are already
+	// URL encoded and must not be escaped again.
+	queryString := ""
+	if len(req.MultiValueQueryStringParameters) > 0 {
+		for q, l := range req.MultiValueQueryStringParameters {
+			for _, v := range l {
+				if queryString != "" {
+					queryString += "&"
+				}
+				queryString += q + "=" + v
+			}
+		}
+	} else {
+		for q, v := range req.QueryStringParameters {
+			if queryString != "" {
+				queryString += "&"
+			}
+			queryString += q + "=" + v
+		}
+	}
+	if queryString != "" {
+		path += "?" + queryString
+	}
+
+	httpRequest, err := http.NewRequest(
+		strings.ToUpper(req.HTTPMethod),
+		path,
+		bytes.NewReader(decodedBody),
+	)
+
+	if err != nil {
+		fmt.Printf("Could not convert request %s:%s to http.Request\n", req.HTTPMethod, req.Path)
+		log.Println(err)
+		return nil, err
+	}
+
+	httpRequest.Header = headers
+	httpRequest.RequestURI = httpRequest.URL.RequestURI()
+
+	return httpRequest, nil
+}
+
+func addALBToContext(ctx context.Context, req *http.Request, albRequest events.ALBTargetGroupRequest) *http.Request {
+	lc, _ := lambdacontext.FromContext(ctx)
+	rc := requestContext{source: sourceALB, lambdaContext: lc, albContext: albRequest.RequestContext}
+	ctx = context.WithValue(ctx, ctxKey{}, rc)
+	return req.WithContext(ctx)
+}
+
+// GetALBContextFromContext retrieve ALBTargetGroupRequestContext from context.Context. It reports false unless the
+// request was created from an events.ALBTargetGroupRequest.
+func GetALBContextFromContext(ctx context.Context) (events.ALBTargetGroupRequestContext, bool) {
+	v, ok := ctx.Value(ctxKey{}).(requestContext)
+	return v.albContext, ok && v.source == sourceALB
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// EventToRequestV2WithContext converts an API Gateway HTTP API (payload format 2.0) or Lambda Function URL event and
+// context into an http.Request object.
+// Returns the populated http request with lambda context, stage variables and APIGatewayV2HTTPRequestContext as part of its context.
+// Access those using GetAPIGatewayV2ContextFromContext, GetStageVarsFromContext and GetRuntimeContextFromContext functions in this package.
+func (r *RequestAccessor) EventToRequestV2WithContext(ctx context.Context, req events.APIGatewayV2HTTPRequest) (*http.Request, error) {
+	httpRequest, err := r.EventToRequestV2(req)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return addV2ToContext(ctx, httpRequest, req), nil
+}
+
+// EventToRequestV2 converts an API Gateway HTTP API (payload format 2.0) or Lambda Function URL event into an
+// http.Request object.
+// Returns the populated request maintaining headers, cookies and the raw query string.
+func (r *RequestAccessor) EventToRequestV2(req events.APIGatewayV2HTTPRequest) (*http.Request, error) {
+	decodedBody := []byte(req.Body)
+	if req.IsBase64Encoded {
+		base64Body, err := base64.StdEncoding.DecodeString(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		decodedBody = base64Body
+	}
+
+	path := r.requestURL(req.RawPath, req.RequestContext.DomainName)
+	if req.RawQueryString != "" {
+		path += "?" + req.RawQueryString
+	}
+
+	method := req.RequestContext.HTTP.Method
+	httpRequest, err := http.NewRequest(
+		strings.ToUpper(method),
+		path,
+		bytes.NewReader(decodedBody),
+	)
+
+	if err != nil {
+		fmt.Printf("Could not convert request %s:%s to http.Request\n", method, req.RawPath)
+		log.Println(err)
+		return nil, err
+	}
+
+	// Payload format 2.0 joins repeated headers with commas, which is equivalent for every header but Cookie. Cookies
+	// are delivered separately.
+	for h, v := range req.Headers {
+		httpRequest.Header.Add(h, v)
+	}
+	if len(req.Cookies) > 0 {
+		httpRequest.Header.Set("Cookie", strings.Join(req.Cookies, "; "))
+	}
+
+	httpRequest.RemoteAddr = req.RequestContext.HTTP.SourceIP
+	httpRequest.RequestURI = httpRequest.URL.RequestURI()
+
+	return httpRequest, nil
+}
+
+func addV2ToContext(ctx context.Context, req *http.Request, apiGwRequest events.APIGatewayV2HTTPRequest) *http.Request {
+	lc, _ := lambdacontext.FromContext(ctx)
+	rc := requestContext{source: sourceAPIGatewayV2, lambdaContext: lc, gatewayV2ProxyContext: apiGwRequest.RequestContext, stageVars: apiGwRequest.StageVariables}
+	ctx = context.WithValue(ctx, ctxKey{}, rc)
+	return req.WithContext(ctx)
+}
+
+// GetAPIGatewayV2ContextFromContext retrieve APIGatewayV2HTTPRequestContext from context.Context. It reports false
+// unless the request was created from an events.APIGatewayV2HTTPRequest.
+func GetAPIGatewayV2ContextFromContext(ctx context.Context) (events.APIGatewayV2HTTPRequestContext, bool) {
+	v, ok := ctx.Value(ctxKey{}).(requestContext)
+	return v.gatewayV2ProxyContext, ok && v.source == sourceAPIGatewayV2
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -13,6 +15,7 @@ import (
 const (
 	defaultStatusCode    = -1
 	contentTypeHeaderKey = "Content-Type"
+	setCookieHeaderKey   = "Set-Cookie"
 )
 
 func GatewayTimeout() events.APIGatewayProxyResponse {
@@ -22,10 +25,11 @@ func GatewayTimeout() events.APIGatewayProxyResponse {
 // ProxyResponseWriter implements http.ResponseWriter and adds the method
 // necessary to return an events.APIGatewayProxyResponse object
 type ProxyResponseWriter struct {
-	headers   http.Header
-	body      bytes.Buffer
-	status    int
-	observers []chan<- bool
+	headers        http.Header
+	body           bytes.Buffer
+	status         int
+	options        ProxyOptions
+	acceptEncoding string
 }
 
 // NewProxyResponseWriter returns a new ProxyResponseWriter object.
@@ -33,25 +37,18 @@ type ProxyResponseWriter struct {
 // status code of -1
 func NewProxyResponseWriter() *ProxyResponseWriter {
 	return &ProxyResponseWriter{
-		headers:   make(http.Header),
-		status:    defaultStatusCode,
-		observers: make([]chan<- bool, 0),
+		headers: make(http.Header),
+		status:  defaultStatusCode,
 	}
-
 }
 
-func (r *ProxyResponseWriter) CloseNotify() <-chan bool {
-	ch := make(chan bool, 1)
-
-	r.observers = append(r.observers, ch)
-
-	return ch
-}
-
-func (r *ProxyResponseWriter) notifyClosed() {
-	for _, v := range r.observers {
-		v <- true
-	}
+// NewProxyResponseWriterWithOptions returns a new ProxyResponseWriter object that encodes the body according to the
+// options. The acceptEncoding value of the request is used to negotiate compression.
+func NewProxyResponseWriterWithOptions(options ProxyOptions, acceptEncoding string) *ProxyResponseWriter {
+	w := NewProxyResponseWriter()
+	w.options = options
+	w.acceptEncoding = acceptEncoding
+	return w
 }
 
 // Header implementation from the http.ResponseWriter interface.
@@ -89,28 +86,133 @@ func (r *ProxyResponseWriter) WriteHeader(status int) {
 // Returns a populated proxy response object. If the response is invalid, for example
 // has no headers or an invalid status code returns an error.
 func (r *ProxyResponseWriter) GetProxyResponse() (events.APIGatewayProxyResponse, error) {
-	r.notifyClosed()
-
 	if r.status == defaultStatusCode {
 		return events.APIGatewayProxyResponse{}, errors.New("Status code not set on response")
 	}
 
-	var output string
-	isBase64 := false
+	output, isBase64, err := r.encodeBody(MaxLambdaPayloadSize)
+	if err != nil {
+		return events.APIGatewayProxyResponse{}, err
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode:        r.status,
+		MultiValueHeaders: http.Header(r.headers),
+		Body:              output,
+		IsBase64Encoded:   isBase64,
+	}, nil
+}
+
+// GetProxyResponseV2 converts the data passed to the response writer into an events.APIGatewayV2HTTPResponse object
+// for API Gateway HTTP APIs and Lambda Function URLs. Repeated headers are joined with commas and Set-Cookie headers
+// are returned in the Cookies field as payload format 2.0 requires.
+// Returns a populated proxy response object. If the response is invalid, for example
+// has no headers or an invalid status code returns an error.
+func (r *ProxyResponseWriter) GetProxyResponseV2() (events.APIGatewayV2HTTPResponse, error) {
+	if r.status == defaultStatusCode {
+		return events.APIGatewayV2HTTPResponse{}, errors.New("Status code not set on response")
+	}
+
+	output, isBase64, err := r.encodeBody(MaxLambdaPayloadSize)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+
+	headers, cookies := v2Headers(r.headers)
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode:      r.status,
+		Headers:         headers,
+		Cookies:         cookies,
+		Body:            output,
+		IsBase64Encoded: isBase64,
+	}, nil
+}
+
+// v2Headers joins repeated headers with commas and separates the Set-Cookie headers, which payload format 2.0 returns
+// in their own field.
+func v2Headers(h http.Header) (map[string]string, []string) {
+	headers := make(map[string]string)
+	var cookies []string
+	for k, v := range h {
+		if k == setCookieHeaderKey {
+			cookies = append(cookies, v...)
+			continue
+		}
+		headers[k] = strings.Join(v, ",")
+	}
+	return headers, cookies
+}
 
+// encodeBody returns the response body as a string. Text bodies are compressed when compression is enabled and
+// accepted by the client. Compressed bodies, bodies matching one of the binary media types and bodies that are not
+// valid UTF-8 are base64 encoded. A PayloadTooLargeError is returned if the encoded body exceeds the limit.
+func (r *ProxyResponseWriter) encodeBody(limit int) (string, bool, error) {
 	bb := (&r.body).Bytes()
+	contentType := r.headers.Get(contentTypeHeaderKey)
+	binary := r.options.isBinary(contentType)
+
+	if r.options.Compress && len(bb) >= r.options.CompressMinSize &&
+		r.headers.Get(contentEncodingHeaderKey) == "" && isCompressible(contentType) {
+		if encoding := negotiateEncoding(r.acceptEncoding); encoding != "" {
+			compressed, err := compress(encoding, bb)
+			if err != nil {
+				return "", false, err
+			}
+			bb = compressed
+			binary = true
+			r.headers.Set(contentEncodingHeaderKey, encoding)
+			r.headers.Add("Vary", "Accept-Encoding")
+			r.headers.Del("Content-Length")
+		}
+	}
 
-	if utf8.Valid(bb) {
-		output = string(bb)
-	} else {
+	output := string(bb)
+	isBase64 := false
+	if binary || !utf8.Valid(bb) {
 		output = base64.StdEncoding.EncodeToString(bb)
 		isBase64 = true
 	}
 
-	return events.APIGatewayProxyResponse{
+	if r.options.MaxPayloadSize > 0 {
+		limit = r.options.MaxPayloadSize
+	}
+	if len(output) > limit {
+		return "", false, &PayloadTooLargeError{Size: len(output), Limit: limit}
+	}
+
+	return output, isBase64, nil
+}
+
+// GetALBResponse converts the data passed to the response writer into an events.ALBTargetGroupResponse object. When
+// multiValue is true the headers are returned in MultiValueHeaders, which the target group requires when multi value
+// headers are enabled. Otherwise only the last value of each header is returned in Headers.
+// Returns a populated response object. If the response is invalid, for example
+// has no headers or an invalid status code returns an error.
+func (r *ProxyResponseWriter) GetALBResponse(multiValue bool) (events.ALBTargetGroupResponse, error) {
+	if r.status == defaultStatusCode {
+		return events.ALBTargetGroupResponse{}, errors.New("Status code not set on response")
+	}
+
+	output, isBase64, err := r.encodeBody(MaxALBPayloadSize)
+	if err != nil {
+		return events.ALBTargetGroupResponse{}, err
+	}
+
+	resp := events.ALBTargetGroupResponse{
 		StatusCode:        r.status,
-		MultiValueHeaders: http.Header(r.headers),
+		StatusDescription: fmt.Sprintf("%d %s", r.status, http.StatusText(r.status)),
 		Body:              output,
 		IsBase64Encoded:   isBase64,
-	}, nil
+	}
+
+	if multiValue {
+		resp.MultiValueHeaders = http.Header(r.headers)
+	} else {
+		resp.Headers = make(map[string]string)
+		for k, v := range r.headers {
+			resp.Headers[k] = v[len(v)-1]
+		}
+	}
+
+	return resp, nil
 }
@@ -0,0 +1,101 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// StreamingResponseWriter implements http.ResponseWriter and http.Flusher for Lambda Function URLs configured with the
+// RESPONSE_STREAM invoke mode. The status code and headers are sent by the first call to Write or Flush, after which the
+// body is piped to the Lambda runtime as the handler writes it instead of being buffered until the handler returns.
+// Streamed bodies are not compressed and are not subject to the buffered payload limit.
+type StreamingResponseWriter struct {
+	headers http.Header
+	status  int
+	reader  *io.PipeReader
+	writer  *io.PipeWriter
+	ready   chan struct{}
+	once    sync.Once
+}
+
+// NewStreamingResponseWriter returns a new StreamingResponseWriter object.
+// The object is initialized with an empty map of headers and a
+// status code of -1
+func NewStreamingResponseWriter() *StreamingResponseWriter {
+	reader, writer := io.Pipe()
+	return &StreamingResponseWriter{
+		headers: make(http.Header),
+		status:  defaultStatusCode,
+		reader:  reader,
+		writer:  writer,
+		ready:   make(chan struct{}),
+	}
+}
+
+// Header implementation from the http.ResponseWriter interface. Changes made after the first call to Write or Flush are
+// not sent to the client.
+func (w *StreamingResponseWriter) Header() http.Header {
+	return w.headers
+}
+
+// WriteHeader sets a status code for the response. It has no effect once the response has been committed.
+func (w *StreamingResponseWriter) WriteHeader(status int) {
+	select {
+	case <-w.ready:
+	default:
+		w.status = status
+	}
+}
+
+// Write commits the status code and headers if needed and blocks until the Lambda runtime has read the body. If no
+// status code was set before with the WriteHeader method it sets the status for the response to 200 OK.
+func (w *StreamingResponseWriter) Write(body []byte) (int, error) {
+	if w.Header().Get(contentTypeHeaderKey) == "" {
+		w.Header().Add(contentTypeHeaderKey, http.DetectContentType(body))
+	}
+	w.commit()
+	return w.writer.Write(body)
+}
+
+// Flush commits the status code and headers. Every call to Write is delivered to the runtime before it returns, so
+// there is no buffered body to flush.
+func (w *StreamingResponseWriter) Flush() {
+	w.commit()
+}
+
+func (w *StreamingResponseWriter) commit() {
+	w.once.Do(func() {
+		if w.status == defaultStatusCode {
+			w.status = http.StatusOK
+		}
+		close(w.ready)
+	})
+}
+
+// Close commits the response and ends the body. A non-nil err is returned to the reader of the body, which aborts the
+// stream. If nothing has been written yet the status is set to 500 Internal Server Error.
+func (w *StreamingResponseWriter) Close(err error) {
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	w.commit()
+	w.writer.CloseWithError(err)
+}
+
+// GetStreamingResponse waits until the response has been committed and converts it into an
+// events.LambdaFunctionURLStreamingResponse whose body is read from the data passed to the writer. Set-Cookie headers
+// are returned in the Cookies field as the Function URL payload requires.
+func (w *StreamingResponseWriter) GetStreamingResponse() *events.LambdaFunctionURLStreamingResponse {
+	<-w.ready
+
+	headers, cookies := v2Headers(w.headers)
+	return &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: w.status,
+		Headers:    headers,
+		Cookies:    cookies,
+		Body:       w.reader,
+	}
+}
@@ -0,0 +1,387 @@
+package router
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/kgrunwald/goweb/ctx"
+	"github.com/kgrunwald/goweb/di"
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProxyTestSuite struct {
+	suite.Suite
+	router *muxRouter
+}
+
+func TestProxyTestSuite(t *testing.T) {
+	suite.Run(t, new(ProxyTestSuite))
+}
+
+func (s *ProxyTestSuite) SetupTest() {
+	s.router = NewRouter(ilog.NewLogger(), di.GetContainer()).(*muxRouter)
+	s.router.Route("/items/{id}", func(c ctx.Context, id int, p listParams) (int, map[string]interface{}, error) {
+		http.SetCookie(c.Writer(), &http.Cookie{Name: "a", Value: "1"})
+		http.SetCookie(c.Writer(), &http.Cookie{Name: "b", Value: "2"})
+		apiGw, _ := GetAPIGatewayV2ContextFromContext(c.Request().Context())
+		return http.StatusCreated, map[string]interface{}{
+			"id":        id,
+			"tags":      p.Tags,
+			"token":     p.Token,
+			"tenant":    p.Tenant,
+			"requestId": apiGw.RequestID,
+			"remote":    c.Request().RemoteAddr,
+		}, nil
+	})
+}
+
+func (s *ProxyTestSuite) TestLambdaHandler() {
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod:                      "GET",
+		Path:                            "/items/5",
+		MultiValueQueryStringParameters: map[string][]string{"tag": {"a", "b"}},
+		MultiValueHeaders:               map[string][]string{"X-Tenant": {"acme"}},
+	}
+
+	resp, err := s.router.lambdaHandler(context.Background(), event)
+	s.Require().NoError(err)
+	s.Equal(http.StatusCreated, resp.StatusCode)
+	s.JSONEq(`{"id": 5, "tags": ["a", "b"], "token": "", "tenant": "acme", "requestId": "", "remote": ""}`, resp.Body)
+	s.Len(resp.MultiValueHeaders["Set-Cookie"], 2)
+}
+
+func (s *ProxyTestSuite) TestLambdaHandlerV2() {
+	event := events.APIGatewayV2HTTPRequest{
+		Version:        "2.0",
+		RawPath:        "/items/5",
+		RawQueryString: "tag=a&tag=b",
+		Cookies:        []string{"session=s1", "other=x"},
+		Headers:        map[string]string{"x-tenant": "acme"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			DomainName: "api.example.com",
+			RequestID:  "req-1",
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:   "GET",
+				Path:     "/items/5",
+				SourceIP: "10.0.0.1",
+			},
+		},
+	}
+
+	resp, err := s.router.lambdaHandlerV2(context.Background(), event)
+	s.Require().NoError(err)
+	s.Equal(http.StatusCreated, resp.StatusCode)
+	s.JSONEq(`{"id": 5, "tags": ["a", "b"], "token": "s1", "tenant": "acme", "requestId": "req-1", "remote": "10.0.0.1"}`, resp.Body)
+	s.Equal([]string{"a=1", "b=2"}, resp.Cookies)
+	s.Equal("application/json", resp.Headers["Content-Type"])
+	s.NotContains(resp.Headers, "Set-Cookie")
+}
+
+func (s *ProxyTestSuite) TestStripBasePathV2() {
+	s.router.StripBasePath("v1")
+	event := events.APIGatewayV2HTTPRequest{
+		RawPath: "/v1/items/7",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+		},
+	}
+
+	resp, err := s.router.lambdaHandlerV2(context.Background(), event)
+	s.Require().NoError(err)
+	s.Equal(http.StatusCreated, resp.StatusCode)
+}
+
+func (s *ProxyTestSuite) TestLambdaHandlerALB() {
+	event := events.ALBTargetGroupRequest{
+		HTTPMethod:            "GET",
+		Path:                  "/items/5",
+		QueryStringParameters: map[string]string{"tag": "a%20b"},
+		Headers:               map[string]string{"host": "lb.example.com", "x-tenant": "acme", "cookie": "session=s1"},
+	}
+
+	resp, err := s.router.lambdaHandlerALB(context.Background(), event)
+	s.Require().NoError(err)
+	s.Equal(http.StatusCreated, resp.StatusCode)
+	s.Equal("201 Created", resp.StatusDescription)
+	s.JSONEq(`{"id": 5, "tags": ["a b"], "token": "s1", "tenant": "acme", "requestId": "", "remote": ""}`, resp.Body)
+	s.Equal("b=2", resp.Headers["Set-Cookie"])
+	s.Nil(resp.MultiValueHeaders)
+}
+
+func (s *ProxyTestSuite) TestLambdaHandlerALBMultiValue() {
+	event := events.ALBTargetGroupRequest{
+		HTTPMethod:                      "GET",
+		Path:                            "/items/5",
+		MultiValueQueryStringParameters: map[string][]string{"tag": {"a", "b"}},
+		MultiValueHeaders:               map[string][]string{"host": {"lb.example.com"}},
+	}
+
+	resp, err := s.router.lambdaHandlerALB(context.Background(), event)
+	s.Require().NoError(err)
+	s.Equal(http.StatusCreated, resp.StatusCode)
+	s.JSONEq(`{"id": 5, "tags": ["a", "b"], "token": "", "tenant": "", "requestId": "", "remote": ""}`, resp.Body)
+	s.Equal([]string{"a=1", "b=2"}, resp.MultiValueHeaders["Set-Cookie"])
+	s.Nil(resp.Headers)
+}
+
+func (s *ProxyTestSuite) TestAPIGatewayEmulator() {
+	s.router.Route("/stage", func(c ctx.Context) (map[string]interface{}, error) {
+		apiGw, _ := GetAPIGatewayContextFromContext(c.Request().Context())
+		stageVars, _ := GetStageVarsFromContext(c.Request().Context())
+		return map[string]interface{}{"stage": apiGw.Stage, "vars": stageVars, "ip": apiGw.Identity.SourceIP}, nil
+	})
+	s.router.Route("/binary", func(c ctx.Context) error {
+		c.Writer().Header().Set("Content-Type", "application/octet-stream")
+		_, err := c.Writer().Write([]byte{0xff, 0xfe, 0x00})
+		return err
+	})
+	s.router.StripBasePath("v1")
+	emulator := NewAPIGatewayEmulator(s.router, ilog.NewLogger(), "dev", map[string]string{"env": "test"})
+
+	req := httptest.NewRequest("GET", "/v1/items/5?tag=a&tag=b", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+	w := httptest.NewRecorder()
+	emulator.ServeHTTP(w, req)
+	s.Equal(http.StatusCreated, w.Code)
+	s.Equal([]string{"a=1", "b=2"}, w.Result().Header["Set-Cookie"])
+	s.Contains(w.Body.String(), `"tags":["a","b"]`)
+	s.Contains(w.Body.String(), `"token":"s1"`)
+
+	w = httptest.NewRecorder()
+	emulator.ServeHTTP(w, httptest.NewRequest("GET", "/v1/stage", nil))
+	s.JSONEq(`{"stage": "dev", "vars": {"env": "test"}, "ip": "192.0.2.1"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	emulator.ServeHTTP(w, httptest.NewRequest("GET", "/v1/binary", nil))
+	s.Equal([]byte{0xff, 0xfe, 0x00}, w.Body.Bytes())
+}
+
+func (s *ProxyTestSuite) TestBinaryMediaTypes() {
+	s.router.Route("/image", func(c ctx.Context) error {
+		c.Writer().Header().Set("Content-Type", "image/svg+xml")
+		_, err := c.Writer().Write([]byte("<svg/>"))
+		return err
+	})
+	s.router.SetProxyOptions(ProxyOptions{BinaryMediaTypes: []string{"image/*"}})
+
+	resp, err := s.router.lambdaHandler(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/image"})
+	s.Require().NoError(err)
+	s.True(resp.IsBase64Encoded)
+	s.Equal(base64.StdEncoding.EncodeToString([]byte("<svg/>")), resp.Body)
+}
+
+func (s *ProxyTestSuite) TestCompression() {
+	body := strings.Repeat("compress me ", 200)
+	s.router.Route("/text", func(c ctx.Context) (string, error) {
+		return body, nil
+	})
+	s.router.SetProxyOptions(ProxyOptions{Compress: true, CompressMinSize: DefaultCompressMinSize})
+
+	for encoding, reader := range map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	} {
+		event := events.APIGatewayProxyRequest{
+			HTTPMethod:        "GET",
+			Path:              "/text",
+			MultiValueHeaders: map[string][]string{"Accept-Encoding": {encoding + ", deflate"}},
+		}
+		resp, err := s.router.lambdaHandler(context.Background(), event)
+		s.Require().NoError(err)
+		s.True(resp.IsBase64Encoded)
+		s.Equal([]string{encoding}, resp.MultiValueHeaders["Content-Encoding"])
+
+		compressed, err := base64.StdEncoding.DecodeString(resp.Body)
+		s.Require().NoError(err)
+		r, err := reader(bytes.NewReader(compressed))
+		s.Require().NoError(err)
+		decoded, err := ioutil.ReadAll(r)
+		s.Require().NoError(err)
+		s.Contains(string(decoded), body)
+	}
+
+	resp, err := s.router.lambdaHandler(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/text"})
+	s.Require().NoError(err)
+	s.False(resp.IsBase64Encoded)
+	s.Empty(resp.MultiValueHeaders["Content-Encoding"])
+}
+
+func (s *ProxyTestSuite) TestPayloadTooLarge() {
+	s.router.Route("/large", func(c ctx.Context) (string, error) {
+		return strings.Repeat("x", 100), nil
+	})
+	s.router.SetProxyOptions(ProxyOptions{MaxPayloadSize: 50})
+
+	resp, err := s.router.lambdaHandler(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/large"})
+	s.Require().NoError(err)
+	s.Equal(http.StatusInternalServerError, resp.StatusCode)
+	s.Contains(resp.Body, "exceeds the maximum Lambda response size of 50 bytes")
+}
+
+func (s *ProxyTestSuite) TestNegotiateEncoding() {
+	s.Equal("br", negotiateEncoding("gzip, deflate, br"))
+	s.Equal("gzip", negotiateEncoding("gzip;q=1.0, br;q=0"))
+	s.Equal("", negotiateEncoding("identity"))
+	s.Equal("", negotiateEncoding(""))
+}
+
+func (s *ProxyTestSuite) TestLambdaHandlerStream() {
+	release := make(chan struct{})
+	s.router.Route("/export", func(c ctx.Context) {
+		w := c.Writer()
+		w.Header().Set(contentTypeHeaderKey, "text/event-stream")
+		http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+		w.WriteHeader(http.StatusAccepted)
+		w.(http.Flusher).Flush()
+		<-release
+		io.WriteString(w, "data: done\n\n")
+	})
+
+	event := events.APIGatewayV2HTTPRequest{
+		RawPath:        "/export",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"}},
+	}
+
+	resp, err := s.router.lambdaHandlerStream(context.Background(), event)
+	s.Require().NoError(err)
+	s.Equal(http.StatusAccepted, resp.StatusCode)
+	s.Equal("text/event-stream", resp.Headers[contentTypeHeaderKey])
+	s.Equal([]string{"a=1"}, resp.Cookies)
+
+	close(release)
+	body, err := ioutil.ReadAll(resp.Body)
+	s.Require().NoError(err)
+	s.Equal("data: done\n\n", string(body))
+}
+
+func (s *ProxyTestSuite) TestLambdaHandlerStreamPanic() {
+	s.router.Route("/panic", func(c ctx.Context) {
+		io.WriteString(c.Writer(), "partial")
+		c.Writer().(http.Flusher).Flush()
+		panic("boom")
+	})
+
+	event := events.APIGatewayV2HTTPRequest{
+		RawPath:        "/panic",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"}},
+	}
+
+	resp, err := s.router.lambdaHandlerStream(context.Background(), event)
+	s.Require().NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	s.Equal("partial", string(body))
+	s.EqualError(err, "Panic while streaming response: "+http.ErrAbortHandler.Error())
+}
+
+func (s *ProxyTestSuite) TestLambdaDeadline() {
+	release := make(chan struct{})
+	defer close(release)
+	s.router.Route("/slow", func(c ctx.Context) error {
+		_, ok := c.Deadline()
+		s.True(ok)
+		<-release
+		return nil
+	})
+	s.router.SetProxyOptions(ProxyOptions{DeadlineMargin: 50 * time.Millisecond})
+
+	c, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := s.router.lambdaHandler(c, events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/slow"})
+	s.Require().NoError(err)
+	s.Less(int64(time.Since(start)), int64(100*time.Millisecond))
+	s.Equal(http.StatusGatewayTimeout, resp.StatusCode)
+	s.JSONEq(`{"error": "Request timed out"}`, resp.Body)
+}
+
+func (s *ProxyTestSuite) TestLambdaDeadlineNotReached() {
+	s.router.Route("/fast", func(c ctx.Context) (string, error) {
+		return "ok", nil
+	})
+
+	c, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	resp, err := s.router.lambdaHandler(c, events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/fast"})
+	s.Require().NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func (s *ProxyTestSuite) TestWithLambdaDeadline() {
+	deadline := time.Now().Add(time.Minute)
+	parent, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	c, cancel := WithLambdaDeadline(parent, time.Second)
+	defer cancel()
+	got, ok := c.Deadline()
+	s.True(ok)
+	s.Equal(deadline.Add(-time.Second), got)
+
+	c, cancel = WithLambdaDeadline(context.Background(), time.Second)
+	defer cancel()
+	_, ok = c.Deadline()
+	s.False(ok)
+}
+
+func (s *ProxyTestSuite) TestLambdaRequestID() {
+	s.router.Route("/id", func(c ctx.Context) (string, error) {
+		return c.RequestID(), nil
+	})
+
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod:     "GET",
+		Path:           "/id",
+		RequestContext: events.APIGatewayProxyRequestContext{RequestID: "apigw-id"},
+	}
+	resp, err := s.router.lambdaHandler(context.Background(), event)
+	s.Require().NoError(err)
+	s.JSONEq(`"apigw-id"`, resp.Body)
+	s.Equal("apigw-id", http.Header(resp.MultiValueHeaders).Get(ctx.HeaderRequestID))
+
+	alb := events.ALBTargetGroupRequest{HTTPMethod: "GET", Path: "/id"}
+	lc := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "invocation-id"})
+	albResp, err := s.router.lambdaHandlerALB(lc, alb)
+	s.Require().NoError(err)
+	s.JSONEq(`"invocation-id"`, albResp.Body)
+}
+
+func (s *ProxyTestSuite) TestEventContextSource() {
+	type sources struct{ v1, v2, alb, stageVars bool }
+	check := func(c context.Context) sources {
+		_, v1 := GetAPIGatewayContextFromContext(c)
+		_, v2 := GetAPIGatewayV2ContextFromContext(c)
+		_, alb := GetALBContextFromContext(c)
+		_, stageVars := GetStageVarsFromContext(c)
+		return sources{v1, v2, alb, stageVars}
+	}
+
+	req, err := s.router.EventToRequestWithContext(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: "GET", Path: "/"})
+	s.Require().NoError(err)
+	s.Equal(sources{v1: true, stageVars: true}, check(req.Context()))
+
+	req, err = s.router.EventToRequestV2WithContext(context.Background(), events.APIGatewayV2HTTPRequest{RawPath: "/"})
+	s.Require().NoError(err)
+	s.Equal(sources{v2: true, stageVars: true}, check(req.Context()))
+
+	req, err = s.router.EventToRequestALBWithContext(context.Background(), events.ALBTargetGroupRequest{HTTPMethod: "GET", Path: "/"})
+	s.Require().NoError(err)
+	s.Equal(sources{alb: true}, check(req.Context()))
+
+	s.Equal(sources{}, check(context.Background()))
+}
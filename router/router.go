@@ -1,15 +1,17 @@
 package router
 
 import (
+	"bufio"
 	"context"
-	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
-	"strconv"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -17,6 +19,8 @@ import (
 	"github.com/kgrunwald/goweb/ctx"
 	"github.com/kgrunwald/goweb/di"
 	"github.com/kgrunwald/goweb/ilog"
+	"github.com/kgrunwald/goweb/openapi"
+	"github.com/kgrunwald/goweb/pubsub"
 )
 
 func init() {
@@ -32,23 +36,103 @@ type Router interface {
 	// Return a subrouter for this router
 	Subrouter(path string) Router
 
+	// Group calls fn with a subrouter for the path prefix. Routes added by fn are registered under the prefix and
+	// middleware added to the group with Use only runs for those routes. Groups may be nested and inherit the name
+	// prefix of their parent. The group is returned so that more routes can be added to it later.
+	Group(prefix string, fn func(Router)) Router
+
+	// NamePrefix appends to the prefix that is prepended to the names of routes created by this Router and its groups
+	// from now on. Groups start with the prefix of their parent, so a group calling `NamePrefix("users.")` inside a
+	// router calling `NamePrefix("api.")` names its `list` route `api.users.list`.
+	NamePrefix(prefix string) Router
+
 	// Serve an SPA at the url pathPrefix using files stored at staticPath
 	ServeSPA(pathPrefix, staticPath string)
 
+	// EnableCORS applies DefaultCORSPolicy to the routes of this Router. See CORS.
+	//
+	// Earlier versions allowed every origin. EnableCORS now allows only the origins listed in CORSOriginsVariable and
+	// logs a warning when the variable is empty, because cross-origin requests are then rejected. To keep the previous
+	// behaviour, set the variable to `*` or pass a policy with the origins your clients use to CORS.
 	EnableCORS() Router
 
+	// CORS applies the policy to the routes of this Router and its groups, unless they override it with Route.CORS
+	// or a policy of their own. Preflight requests are answered with the methods the routes matching the path
+	// actually accept, while preflight requests for paths without a route receive the usual 404 response.
+	CORS(policy CORSPolicy) Router
+
+	// NotFound replaces the handler for requests whose path matches no route of this Router. By default the client
+	// receives a 404 response encoded through ctx.Context in the format it asked for.
+	NotFound(handler http.Handler) Router
+
+	// MethodNotAllowed replaces the handler for requests whose path matches a route of this Router that does not accept
+	// the method. The Allow header listing the accepted methods is set before the handler is called. By default the
+	// client receives a 405 response encoded through ctx.Context in the format it asked for.
+	MethodNotAllowed(handler http.Handler) Router
+
+	// Secure protects the routes of this Router and its groups with the security schemes. Each scheme adds its
+	// middleware, so requests must satisfy all of them, and is listed as a requirement in the OpenAPI document.
+	Secure(schemes ...SecurityScheme) Router
+
+	// OpenAPI returns an OpenAPI 3 document describing the routes of this Router and its groups. Path variables,
+	// parameter structs, request bodies and responses are described from the signatures of the controller methods,
+	// see RouteHandler.Handle. Routes registered with Handler directly, ServeSPA and ServeOpenAPI are omitted.
+	// Security requirements are only known for schemes added with Secure: a route protected by calling
+	// Use(scheme.Middleware) is documented without security.
+	OpenAPI(info openapi.Info) *openapi.Document
+
+	// ServeOpenAPI serves the JSON OpenAPI document of this Router at path. The document is generated on every request
+	// and lists the host the request was received on as its server. NewRouter calls it when OpenAPIPathVariable is set.
+	ServeOpenAPI(path string, info openapi.Info) Route
+
+	// Routes describes the routes of this Router and its groups in the order they were registered, including routes
+	// added with ServeSPA and ServeOpenAPI.
+	Routes() []RouteInfo
+
+	// URL returns the path of the named route, with the variables of its template replaced by params given as
+	// name/value pairs. The base path removed by StripBasePath is added back so the path is valid for clients behind
+	// an API Gateway base path mapping.
+	URL(name string, params ...string) (*url.URL, error)
+
+	// AbsoluteURL is like URL but includes the scheme and host, taken from the CustomHostVariable environment variable
+	// when it is set and from the request otherwise.
+	AbsoluteURL(req *http.Request, name string, params ...string) (*url.URL, error)
+
 	// PathParams should return any URL parameters from the specified route
 	PathParams(req *http.Request) map[string]string
 
-	// Use adds a Middleware handler to the chain of middleware
+	// Use adds a Middleware handler to the chain of middleware. See Middleware for the order in which it runs.
 	Use(fn Middleware) Router
 
-	// Start listening for incoming connections. This function will block.
-	Start(port int)
+	// Start listening for incoming connections. This function will block until the listener fails and return the error.
+	Start(port int) error
 
 	// Start serving requests from AWS Lambda
 	StartLambda()
 
+	// Start serving API Gateway HTTP API (payload format 2.0) and Lambda Function URL requests from AWS Lambda
+	StartLambdaV2()
+
+	// Start serving Application Load Balancer target group requests from AWS Lambda
+	StartLambdaALB()
+
+	// Start serving Lambda Function URL requests from AWS Lambda with the RESPONSE_STREAM invoke mode. Responses are
+	// sent to the client as handlers write them, see StreamingResponseWriter. The function must use the provided
+	// runtime or be built with the lambda.norpc tag.
+	StartLambdaStream()
+
+	// SetProxyOptions configures how responses are encoded for AWS Lambda. The defaults are read from the environment,
+	// see DefaultProxyOptions.
+	SetProxyOptions(options ProxyOptions) Router
+
+	// StripBasePath removes the base path from the path of Lambda events before routing them. This is used when API
+	// Gateway is configured with base path mappings in custom domain names.
+	StripBasePath(basePath string) string
+
+	// HandleLambda serves a single events.APIGatewayProxyRequest, events.APIGatewayV2HTTPRequest or
+	// events.ALBTargetGroupRequest and returns the matching response event.
+	HandleLambda(ctx context.Context, event interface{}) (interface{}, error)
+
 	ServeHTTP(w http.ResponseWriter, req *http.Request)
 }
 
@@ -60,19 +144,61 @@ type Route interface {
 	Methods(...string) Route
 	Headers(...string) Route
 	Handler(f func(http.ResponseWriter, *http.Request)) Route
+
+	// Use adds Middleware that only runs for this route, after the middleware of the routers and groups that contain
+	// it. Middleware runs in the order it is added.
+	Use(fns ...Middleware) Route
+
+	// CORS overrides the CORSPolicy of the router for this route.
+	CORS(policy CORSPolicy) Route
+
+	// Secure protects the route with the security schemes in addition to those of its routers. See Router.Secure.
+	Secure(schemes ...SecurityScheme) Route
 }
 
 type muxRoute struct {
-	route *mux.Route
+	route      *mux.Route
+	router     *muxRouter
+	namePrefix string
+	handler    http.Handler
+	middleware []Middleware
+	cors       *CORSPolicy
+	target     *RouteHandler
+	headers    []string
+	security   []SecurityScheme
 }
 
 func (r *muxRoute) Handler(f func(http.ResponseWriter, *http.Request)) Route {
-	r.route.HandlerFunc(f)
+	r.target = nil
+	r.handler = http.HandlerFunc(f)
+	r.route.Handler(r.chain())
+	return r
+}
+
+func (r *muxRoute) Use(fns ...Middleware) Route {
+	r.middleware = append(r.middleware, fns...)
+	if r.handler != nil {
+		r.route.Handler(r.chain())
+	}
+	return r
+}
+
+func (r *muxRoute) CORS(policy CORSPolicy) Route {
+	r.cors = &policy
 	return r
 }
 
+// chain wraps the handler of the route in its middleware so that the first middleware added runs first.
+func (r *muxRoute) chain() http.Handler {
+	h := r.handler
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+	return h
+}
+
 func (r *muxRoute) Name(name string) Route {
-	r.route.Name(name)
+	r.route.Name(r.namePrefix + name)
 	return r
 }
 
@@ -93,12 +219,19 @@ func (r *muxRoute) Methods(methods ...string) Route {
 
 func (r *muxRoute) Headers(headers ...string) Route {
 	r.route.Headers(headers...)
+	r.headers = append(r.headers, headers...)
 	return r
 }
 
-// Middleware is a function that is invoked before the actual route handler
+// Middleware is a function that is invoked before the actual route handler. For a matched route the middleware added
+// with Router.Use runs first, starting with the root router, followed by the middleware of each enclosing Group or
+// Subrouter from the outermost to the innermost and finally the middleware added with Route.Use. Middleware added at
+// the same level runs in the order it was added. The root router always starts with RequestIDMiddleware, the access log
+// when it is enabled and RecoveryMiddleware, ahead of any middleware added by the application. They run for every
+// request, including requests answered with a 404 or 405 response and CORS preflight requests.
 type Middleware func(next http.Handler) http.Handler
 
+// statusWriter records the status code and the number of bytes written to the response.
 type statusWriter struct {
 	http.ResponseWriter
 	status int
@@ -119,53 +252,155 @@ func (w *statusWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Flush implements http.Flusher for writers that support it, such as StreamingResponseWriter.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for writers that support it so that handlers can upgrade connections, for example to
+// WebSockets. A hijacked connection counts as a started response.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not support hijacking", w.ResponseWriter)
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Push implements http.Pusher for writers that support it, which are HTTP/2 connections.
+func (w *statusWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+var pathVarPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
 type muxRouter struct {
-	RequestAccessor
-	mux    *mux.Router
-	logger ilog.Logger
+	*RequestAccessor
+	mux          *mux.Router
+	parent       *muxRouter
+	registry     *routeRegistry
+	logger       ilog.Logger
+	container    di.Container
+	proxyOptions ProxyOptions
+	namePrefix   string
+	cors         *CORSPolicy
+	security     []SecurityScheme
+	middleware   []Middleware
+
+	// edge holds the middleware that wraps every request served by the root router, and entry the resulting handler.
+	edge  []Middleware
+	entry http.Handler
+}
+
+// routeRegistry is shared by a router and its subrouters and keeps the routes created by Route.
+type routeRegistry struct {
+	routes map[*mux.Route]*muxRoute
+}
+
+// bus returns the pubsub.Bus from the container, or nil if the container does not provide one.
+func (r *muxRouter) bus() pubsub.Bus {
+	busType := reflect.TypeOf((*pubsub.Bus)(nil)).Elem()
+	if r.container == nil || !r.container.Has(busType) {
+		return nil
+	}
+	return r.container.Resolve(busType).Interface().(pubsub.Bus)
 }
 
 // NewRouter returns a concrete implementation of the Router interface
-func NewRouter(logger ilog.Logger) Router {
+func NewRouter(logger ilog.Logger, container di.Container) Router {
 	r := &muxRouter{
-		mux:    mux.NewRouter(),
-		logger: logger,
+		RequestAccessor: &RequestAccessor{},
+		mux:             mux.NewRouter(),
+		registry:        &routeRegistry{routes: map[*mux.Route]*muxRoute{}},
+		logger:          logger,
+		container:       container,
+		proxyOptions:    DefaultProxyOptions(),
 	}
 
-	// r.Use(LogMiddleware(logger))
+	r.NotFound(http.HandlerFunc(r.notFoundHandler))
+	r.MethodNotAllowed(http.HandlerFunc(r.methodNotAllowedHandler))
+	r.mux.Use(recordMatchedRoute)
+	r.useEdge(RequestIDMiddleware)
+	if options, ok := DefaultAccessLogOptions(); ok {
+		r.useEdge(AccessLogMiddleware(logger, options))
+	}
+	r.useEdge(RecoveryMiddleware(logger, r.bus()))
+	if path := os.Getenv(OpenAPIPathVariable); path != "" {
+		r.ServeOpenAPI(path, DefaultOpenAPIInfo())
+	}
 	return r
 }
 
 func (r *muxRouter) Route(path string, method interface{}) Route {
-	route := &muxRoute{r.mux.NewRoute()}
+	route := &muxRoute{route: r.mux.NewRoute(), router: r, namePrefix: r.namePrefix}
 	route.Path(path)
 
 	m := reflect.ValueOf(method)
-	vars := []string{}
-	re := regexp.MustCompile(`\{([^{}]+)\}`)
-	matches := re.FindAllStringSubmatch(route.GetPath(), -1)
-	for _, match := range matches {
-		vars = append(vars, match[1])
+	vars := pathVars(route.GetPath())
+	if err := validateHandler(route.GetPath(), m, vars, r.container); err != nil {
+		panic(err)
 	}
 
 	binding := RouteBinding{
 		Route: route,
 		Vars:  vars,
 	}
-	handler := &RouteHandler{Method: m, Binding: binding, Router: r, Log: r.logger}
+	handler := &RouteHandler{Method: m, Binding: binding, Router: r, Log: r.logger, Container: r.container}
+	handler.resolveServices()
 	route.Handler(handler.Handle)
+	route.target = handler
+	r.registry.routes[route.route] = route
 	return route
 }
 
+// pathVars returns the names of the variables in a route template in the order they appear. Any regular expression
+// following the name, as in `{id:[0-9]+}`, is removed.
+func pathVars(path string) []string {
+	vars := []string{}
+	matches := pathVarPattern.FindAllStringSubmatch(path, -1)
+	for _, match := range matches {
+		vars = append(vars, strings.SplitN(match[1], ":", 2)[0])
+	}
+	return vars
+}
+
 func (r *muxRouter) Subrouter(path string) Router {
 	return &muxRouter{
-		mux:    r.mux.PathPrefix(path).Subrouter(),
-		logger: r.logger,
+		RequestAccessor: r.RequestAccessor,
+		mux:             r.mux.PathPrefix(path).Subrouter(),
+		parent:          r,
+		registry:        r.registry,
+		logger:          r.logger,
+		container:       r.container,
+		proxyOptions:    r.proxyOptions,
+		namePrefix:      r.namePrefix,
 	}
 }
 
+func (r *muxRouter) Group(prefix string, fn func(Router)) Router {
+	group := r.Subrouter(prefix)
+	fn(group)
+	return group
+}
+
+func (r *muxRouter) NamePrefix(prefix string) Router {
+	r.namePrefix += prefix
+	return r
+}
+
 func (r *muxRouter) Use(fn Middleware) Router {
 	r.mux.Use((mux.MiddlewareFunc)(fn))
+	r.middleware = append(r.middleware, fn)
 	return r
 }
 
@@ -179,35 +414,81 @@ func (r *muxRouter) ServeSPA(pathPrefix, staticPath string) {
 }
 
 func (r *muxRouter) EnableCORS() Router {
-	r.mux.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Add("access-control-allow-origin", r.Header.Get("Origin"))
-			w.Header().Add("access-control-allow-headers", "Authorization, X-API-Key, Content-Type")
-			w.Header().Add("access-control-allow-credentials", "true")
-
-			if r.Method == "OPTIONS" {
-				w.Header().Add("access-control-allow-methods", "POST, GET, PUT, DELETE, OPTIONS")
-				w.Header().Add("access-control-max-age", "86400")
-				w.WriteHeader(http.StatusOK)
-				return
-			}
+	policy := DefaultCORSPolicy()
+	if len(policy.AllowedOrigins) == 0 {
+		r.logger.WithField("variable", CORSOriginsVariable).Warn("CORS is enabled but no origins are allowed, set " + CORSOriginsVariable + " to allow cross-origin requests")
+	}
+	return r.CORS(policy)
+}
 
-			next.ServeHTTP(w, r)
-		})
-	})
+func (r *muxRouter) CORS(policy CORSPolicy) Router {
+	r.cors = &policy
 	return r
 }
 
-func (r *muxRouter) Start(port int) {
-	http.ListenAndServe(fmt.Sprintf(":%d", port), r.mux)
+func (r *muxRouter) Start(port int) error {
+	return http.ListenAndServe(fmt.Sprintf(":%d", port), r)
 }
 
 func (r *muxRouter) StartLambda() {
 	lambda.Start(r.lambdaHandler)
 }
 
+func (r *muxRouter) StartLambdaV2() {
+	lambda.Start(r.lambdaHandlerV2)
+}
+
+func (r *muxRouter) StartLambdaALB() {
+	lambda.Start(r.lambdaHandlerALB)
+}
+
+func (r *muxRouter) StartLambdaStream() {
+	lambda.Start(r.lambdaHandlerStream)
+}
+
+func (r *muxRouter) SetProxyOptions(options ProxyOptions) Router {
+	r.proxyOptions = options
+	return r
+}
+
+func (r *muxRouter) HandleLambda(ctx context.Context, event interface{}) (interface{}, error) {
+	switch e := event.(type) {
+	case events.APIGatewayProxyRequest:
+		return r.lambdaHandler(ctx, e)
+	case events.APIGatewayV2HTTPRequest:
+		return r.lambdaHandlerV2(ctx, e)
+	case events.ALBTargetGroupRequest:
+		return r.lambdaHandlerALB(ctx, e)
+	}
+
+	return nil, fmt.Errorf("Unsupported Lambda event type %T", event)
+}
+
+// useEdge adds middleware that runs for every request served by the router, before CORS is handled and a route is
+// matched. Unlike middleware added with Use it also runs for 404 and 405 responses and for preflight requests.
+func (r *muxRouter) useEdge(fn Middleware) {
+	r.edge = append(r.edge, fn)
+	var h http.Handler = http.HandlerFunc(r.dispatch)
+	for i := len(r.edge) - 1; i >= 0; i-- {
+		h = r.edge[i](h)
+	}
+	r.entry = h
+}
+
 func (r *muxRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	r.mux.ServeHTTP(w, req)
+	if r.entry != nil {
+		r.entry.ServeHTTP(w, req)
+		return
+	}
+	r.dispatch(w, req)
+}
+
+// dispatch answers CORS preflight requests and routes every other request.
+func (r *muxRouter) dispatch(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get(headerOrigin) != "" && r.handleCORS(w, req) {
+		return
+	}
+	r.mux.ServeHTTP(w, req.WithContext(ctx.WithURLBuilder(req.Context(), r)))
 }
 
 func (r *muxRouter) lambdaHandler(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
@@ -218,18 +499,104 @@ func (r *muxRouter) lambdaHandler(ctx context.Context, event events.APIGatewayPr
 	return r.proxyInternal(req)
 }
 
-func (r *muxRouter) proxyInternal(req *http.Request) (events.APIGatewayProxyResponse, error) {
-	w := NewProxyResponseWriter()
-	r.mux.ServeHTTP(http.ResponseWriter(w), req)
+func (r *muxRouter) lambdaHandlerV2(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	req, err := r.EventToRequestV2WithContext(ctx, event)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusGatewayTimeout}, fmt.Errorf("Could not convert proxy event to request: %v", err)
+	}
 
-	resp, err := w.GetProxyResponse()
+	resp, err := r.serveProxy(req).GetProxyResponseV2()
+	if tooLarge, ok := err.(*PayloadTooLargeError); ok {
+		r.logPayloadTooLarge(req, tooLarge)
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode: http.StatusInternalServerError,
+			Headers:    map[string]string{contentTypeHeaderKey: "text/plain; charset=utf-8"},
+			Body:       tooLarge.Error(),
+		}, nil
+	} else if err != nil {
+		return events.APIGatewayV2HTTPResponse{StatusCode: http.StatusGatewayTimeout}, fmt.Errorf("Error while generating proxy response: %v", err)
+	}
+
+	return resp, nil
+}
+
+func (r *muxRouter) lambdaHandlerALB(ctx context.Context, event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	req, err := r.EventToRequestALBWithContext(ctx, event)
 	if err != nil {
+		return events.ALBTargetGroupResponse{StatusCode: http.StatusGatewayTimeout}, fmt.Errorf("Could not convert ALB event to request: %v", err)
+	}
+
+	resp, err := r.serveProxy(req).GetALBResponse(event.MultiValueHeaders != nil)
+	if tooLarge, ok := err.(*PayloadTooLargeError); ok {
+		r.logPayloadTooLarge(req, tooLarge)
+		resp = events.ALBTargetGroupResponse{
+			StatusCode:        http.StatusInternalServerError,
+			StatusDescription: "500 Internal Server Error",
+			Body:              tooLarge.Error(),
+		}
+		if event.MultiValueHeaders != nil {
+			resp.MultiValueHeaders = map[string][]string{contentTypeHeaderKey: {"text/plain; charset=utf-8"}}
+		} else {
+			resp.Headers = map[string]string{contentTypeHeaderKey: "text/plain; charset=utf-8"}
+		}
+		return resp, nil
+	} else if err != nil {
+		return events.ALBTargetGroupResponse{StatusCode: http.StatusGatewayTimeout}, fmt.Errorf("Error while generating ALB response: %v", err)
+	}
+
+	return resp, nil
+}
+
+// lambdaHandlerStream returns as soon as the handler commits the response and keeps serving the request in the
+// background while the runtime reads the body.
+func (r *muxRouter) lambdaHandlerStream(ctx context.Context, event events.APIGatewayV2HTTPRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+	req, err := r.EventToRequestV2WithContext(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("Could not convert proxy event to request: %v", err)
+	}
+
+	c, cancel := WithLambdaDeadline(req.Context(), r.proxyOptions.DeadlineMargin)
+	req = withRequestID(req.WithContext(c))
+
+	w := NewStreamingResponseWriter()
+	go func() {
+		var err error
+		defer cancel()
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("Panic while streaming response: %v", p)
+				r.logger.WithFields("method", req.Method, "path", req.URL.Path, "requestID", requestID(req)).Error(err.Error())
+			}
+			w.Close(err)
+		}()
+		r.ServeHTTP(w, req)
+	}()
+
+	return w.GetStreamingResponse(), nil
+}
+
+func (r *muxRouter) proxyInternal(req *http.Request) (events.APIGatewayProxyResponse, error) {
+	resp, err := r.serveProxy(req).GetProxyResponse()
+	if tooLarge, ok := err.(*PayloadTooLargeError); ok {
+		r.logPayloadTooLarge(req, tooLarge)
+		return events.APIGatewayProxyResponse{
+			StatusCode:        http.StatusInternalServerError,
+			MultiValueHeaders: map[string][]string{contentTypeHeaderKey: {"text/plain; charset=utf-8"}},
+			Body:              tooLarge.Error(),
+		}, nil
+	} else if err != nil {
 		return GatewayTimeout(), fmt.Errorf("Error while generating proxy response: %v", err)
 	}
 
 	return resp, nil
 }
 
+// logPayloadTooLarge reports a response that could not be returned through Lambda. The client receives a 500 response
+// explaining the failure instead of the opaque error the platform would return.
+func (r *muxRouter) logPayloadTooLarge(req *http.Request, err *PayloadTooLargeError) {
+	r.logger.WithFields("method", req.Method, "path", req.URL.Path, "requestID", requestID(req), "size", err.Size, "limit", err.Limit).Error(err.Error())
+}
+
 // spaHandler implements the http.Handler interface, so we can use it
 // to respond to HTTP requests. The path to the static directory and
 // path to the index file within that static directory are used to
@@ -281,68 +648,132 @@ type RouteBinding struct {
 
 // RouteHandler implements the HTTP request handler interface by invoking the method specified in the binding.
 type RouteHandler struct {
-	Method  reflect.Value
-	Router  Router
-	Binding RouteBinding
-	Log     ilog.Logger
+	Method    reflect.Value
+	Router    Router
+	Binding   RouteBinding
+	Log       ilog.Logger
+	Container di.Container
+
+	// services holds the arguments resolved from the container by argument index.
+	services map[int]reflect.Value
+}
+
+// resolveServices resolves the service arguments of the controller method once, when the route is created. The
+// container lazily builds services without synchronization, so resolving them while serving concurrent requests is
+// not safe.
+func (h *RouteHandler) resolveServices() {
+	method := h.Method.Type()
+	h.services = map[int]reflect.Value{}
+	for idx := 0; idx < method.NumIn(); idx++ {
+		if classifyParam(method, idx, len(h.Binding.Vars), h.Container) == paramService {
+			h.services[idx] = h.Container.Resolve(method.In(idx))
+		}
+	}
+}
+
+// service returns the service argument at idx, resolving it from the container if the handler was not created by
+// Router.Route.
+func (h *RouteHandler) service(idx int, argType reflect.Type) reflect.Value {
+	if val, ok := h.services[idx]; ok {
+		return val
+	}
+	return h.Container.Resolve(argType)
 }
 
 // Handle is invoked on every incoming HTTP request. It builds up the required parameters for the controller method
-// in the `RouteBinding` by inspecting the types of the method arguments. Currently, only primitive
-// types may be included. It assumes that each parameter in the controller method correlates
-// to a path parameter defined in the `routes.yaml` configuration, and that the parameters are defined in the same order.
-// The controller method MUST return an implementation of `Response`.
+// in the `RouteBinding` by inspecting the types of the method arguments. The first argument receives the `ctx.Context`.
+// It assumes that the following parameters correlate to the path parameters of the route template, defined in the same
+// order. Path parameters may be any primitive type, time.Duration, a type implementing encoding.TextUnmarshaler or a
+// type with a Converter registered via RegisterConverter. Any remaining struct arguments with `query`, `header` or
+// `cookie` tagged fields are populated from the request using the same conversions, and arguments whose type is a
+// service registered in the container are resolved from it by type. A trailing struct, or pointer to struct, argument
+// is decoded from the request body with the Context's negotiated decoder.
+// The controller method may return nothing, `error`, `(T, error)` or `(int, T, error)`; see respond.
 func (h *RouteHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	in := []reflect.Value{}
 	method := h.Method.Type()
 	numArgs := method.NumIn()
 
 	context := ctx.New(r, w, h.Log)
-	if numArgs > 0 {
-		in = append(in, reflect.ValueOf(context))
-
-		if len(h.Binding.Vars) > 0 {
-			vars := h.Router.PathParams(r)
-			for idx, v := range h.Binding.Vars {
-				fieldType := h.Method.Type().In(idx + 1).String()
-				val, err := getArgument(vars[v], fieldType)
-				if err != nil {
-					context.BadRequest(err)
-					return
-				}
-				in = append(in, val)
+	vars := h.Router.PathParams(r)
+	for idx := 0; idx < numArgs; idx++ {
+		argType := method.In(idx)
+		switch classifyParam(method, idx, len(h.Binding.Vars), h.Container) {
+		case paramContext:
+			in = append(in, reflect.ValueOf(context))
+		case paramPath:
+			val, err := getArgument(vars[h.Binding.Vars[idx-1]], argType)
+			if err != nil {
+				context.BadRequest(err)
+				return
+			}
+			in = append(in, val)
+		case paramStruct:
+			val, bindErr := bindParams(r, argType)
+			if bindErr != nil {
+				context.BadRequest(bindErr)
+				return
+			}
+			in = append(in, val)
+		case paramService:
+			in = append(in, h.service(idx, argType))
+		case paramBody:
+			val, err := bindBody(context, argType)
+			if err != nil {
+				context.BadRequest(err)
+				return
 			}
+			in = append(in, val)
+		default:
+			context.SendError(fmt.Errorf("Unsupported argument %d of type %s", idx, argType))
+			return
 		}
 	}
 
-	err := h.Method.Call(in)[0].Interface()
-	if err != nil {
-		context.SendError(err.(error))
-	}
+	h.respond(context, h.Method.Call(in))
 }
 
-func getArgument(val, argType string) (reflect.Value, error) {
-	switch argType {
-	case "string":
-		return reflect.ValueOf(val), nil
-	case "int":
-		v, err := getInt(val)
-		return reflect.ValueOf(int(v)), err
-	case "int32":
-		v, err := getInt(val)
-		return reflect.ValueOf(int32(v)), err
-	case "int64":
-		v, err := getInt(val)
-		return reflect.ValueOf(v), err
+// respond writes the values returned by the controller method. A non-nil error is sent with SendError. Otherwise a
+// returned body is encoded with a 200 status, or with the status code returned alongside it. A nil pointer, slice, map
+// or interface body is not encoded as `null`: the response is a 204 without a body, or the returned status code
+// without a body.
+func (h *RouteHandler) respond(context ctx.Context, out []reflect.Value) {
+	if len(out) == 0 {
+		return
 	}
 
-	return reflect.ValueOf(nil), errors.New("Failed to convert argType: " + argType)
+	if err, ok := out[len(out)-1].Interface().(error); ok && err != nil {
+		context.SendError(err)
+		return
+	}
+
+	switch len(out) {
+	case 2:
+		if isNil(out[0]) {
+			writeEmpty(context, http.StatusNoContent)
+			return
+		}
+		context.OK(out[0].Interface())
+	case 3:
+		if isNil(out[1]) {
+			writeEmpty(context, int(out[0].Int()))
+			return
+		}
+		context.Respond(int(out[0].Int()), out[1].Interface())
+	}
 }
 
-func getInt(val string) (int64, error) {
-	v, err := strconv.ParseInt(val, 10, 64)
-	if err != nil {
-		return 0, err
+// isNil reports whether a value returned by a controller method is a nil pointer, slice, map or interface.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return v.IsNil()
 	}
-	return v, nil
+	return false
+}
+
+// writeEmpty sends a response with the status and the request ID but without a body.
+func writeEmpty(context ctx.Context, status int) {
+	context.Writer().Header().Set(ctx.HeaderRequestID, context.RequestID())
+	context.Writer().WriteHeader(status)
 }
@@ -0,0 +1,517 @@
+package router
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kgrunwald/goweb/apierrors"
+	"github.com/kgrunwald/goweb/ctx"
+	"github.com/kgrunwald/goweb/di"
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/stretchr/testify/suite"
+)
+
+type RouterTestSuite struct {
+	suite.Suite
+	container *di.ServiceContainer
+	router    Router
+}
+
+func TestRouterTestSuite(t *testing.T) {
+	suite.Run(t, new(RouterTestSuite))
+}
+
+func (s *RouterTestSuite) SetupTest() {
+	s.container = di.NewServiceContainer()
+	s.container.Register(ilog.NewLogger)
+	s.router = NewRouter(ilog.NewLogger(), s.container)
+}
+
+func (s *RouterTestSuite) serve(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *RouterTestSuite) TestPathParams() {
+	s.router.Route("/users/{id}/{active}", func(c ctx.Context, id userID, active bool) error {
+		return c.OK(map[string]interface{}{"id": id, "active": active})
+	})
+
+	w := s.serve(httptest.NewRequest("GET", "/users/abc/true", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{"id": "abc", "active": true}`, w.Body.String())
+}
+
+func (s *RouterTestSuite) TestPathParamConversionError() {
+	s.router.Route("/users/{id}", func(c ctx.Context, id int) error {
+		return c.OK(id)
+	})
+
+	w := s.serve(httptest.NewRequest("GET", "/users/abc", nil))
+	s.Equal(http.StatusBadRequest, w.Code)
+}
+
+type listParams struct {
+	Page   int      `query:"page"`
+	Tags   []string `query:"tag"`
+	Tenant string   `header:"X-Tenant"`
+	Token  string   `cookie:"session"`
+}
+
+func (s *RouterTestSuite) TestParamsStruct() {
+	s.router.Route("/users/{id}", func(c ctx.Context, id int, p *listParams) error {
+		return c.OK(map[string]interface{}{"id": id, "page": p.Page, "tags": p.Tags, "tenant": p.Tenant, "token": p.Token})
+	})
+
+	req := httptest.NewRequest("GET", "/users/3?page=2&tag=a&tag=b", nil)
+	req.Header.Set("X-Tenant", "acme")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s3cr3t"})
+	w := s.serve(req)
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{"id": 3, "page": 2, "tags": ["a", "b"], "tenant": "acme", "token": "s3cr3t"}`, w.Body.String())
+}
+
+func (s *RouterTestSuite) TestParamsStructConversionError() {
+	type params struct {
+		Page  int  `query:"page"`
+		Limit uint `header:"X-Limit"`
+	}
+	s.router.Route("/users", func(c ctx.Context, p params) error {
+		return c.OK(p.Page)
+	})
+
+	req := httptest.NewRequest("GET", "/users?page=two", nil)
+	req.Header.Set("X-Limit", "-1")
+	w := s.serve(req)
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(w.Body.String(), `"field":"page","source":"query"`)
+	s.Contains(w.Body.String(), `"field":"X-Limit","source":"header"`)
+}
+
+type createRequest struct {
+	Name string `json:"name"`
+}
+
+func (s *RouterTestSuite) TestBodyBinding() {
+	s.router.Route("/users/{id}", func(c ctx.Context, id int, p listParams, body *createRequest) error {
+		return c.OK(map[string]interface{}{"id": id, "page": p.Page, "name": body.Name})
+	})
+
+	req := httptest.NewRequest("POST", "/users/3?page=4", bytes.NewBufferString(`{"name": "kyle"}`))
+	w := s.serve(req)
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{"id": 3, "page": 4, "name": "kyle"}`, w.Body.String())
+}
+
+func (s *RouterTestSuite) TestBodyBindingError() {
+	called := false
+	s.router.Route("/users", func(c ctx.Context, body createRequest) error {
+		called = true
+		return c.OK(body.Name)
+	})
+
+	w := s.serve(httptest.NewRequest("POST", "/users", bytes.NewBufferString(`{"name": `)))
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.False(called)
+
+	w = s.serve(httptest.NewRequest("POST", "/users", nil))
+	s.Equal(http.StatusBadRequest, w.Code)
+	s.Contains(w.Body.String(), "Request body is required")
+	s.False(called)
+}
+
+func (s *RouterTestSuite) TestReturnValues() {
+	s.router.Route("/none", func(c ctx.Context) {})
+	s.router.Route("/value", func(c ctx.Context) (*createRequest, error) {
+		return &createRequest{Name: "kyle"}, nil
+	})
+	s.router.Route("/status", func(c ctx.Context) (int, createRequest, error) {
+		return http.StatusCreated, createRequest{Name: "kyle"}, nil
+	})
+	s.router.Route("/error", func(c ctx.Context) (*createRequest, error) {
+		return nil, apierrors.NotFoundError("missing")
+	})
+
+	w := s.serve(httptest.NewRequest("GET", "/none", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.Empty(w.Body.String())
+
+	w = s.serve(httptest.NewRequest("GET", "/value", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.JSONEq(`{"name": "kyle"}`, w.Body.String())
+
+	w = s.serve(httptest.NewRequest("GET", "/status", nil))
+	s.Equal(http.StatusCreated, w.Code)
+	s.JSONEq(`{"name": "kyle"}`, w.Body.String())
+
+	w = s.serve(httptest.NewRequest("GET", "/error", nil))
+	s.Equal(http.StatusNotFound, w.Code)
+	s.JSONEq(`{"error": "missing"}`, w.Body.String())
+}
+
+func (s *RouterTestSuite) TestNilReturnValues() {
+	s.router.Route("/pointer", func(c ctx.Context) (*createRequest, error) { return nil, nil })
+	s.router.Route("/slice", func(c ctx.Context) ([]string, error) { return nil, nil })
+	s.router.Route("/map", func(c ctx.Context) (map[string]int, error) { return nil, nil })
+	s.router.Route("/interface", func(c ctx.Context) (interface{}, error) { return nil, nil })
+	s.router.Route("/status", func(c ctx.Context) (int, *createRequest, error) { return http.StatusAccepted, nil, nil })
+	s.router.Route("/empty", func(c ctx.Context) ([]string, error) { return []string{}, nil })
+
+	for _, path := range []string{"/pointer", "/slice", "/map", "/interface"} {
+		w := s.serve(httptest.NewRequest("GET", path, nil))
+		s.Equal(http.StatusNoContent, w.Code, path)
+		s.Empty(w.Body.String(), path)
+		s.NotEmpty(w.Header().Get(ctx.HeaderRequestID), path)
+	}
+
+	w := s.serve(httptest.NewRequest("GET", "/status", nil))
+	s.Equal(http.StatusAccepted, w.Code)
+	s.Empty(w.Body.String())
+
+	w = s.serve(httptest.NewRequest("GET", "/empty", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("[]\n", w.Body.String())
+}
+
+func (s *RouterTestSuite) TestInvalidReturnValues() {
+	s.PanicsWithError("Invalid handler for route /a: last return value must be error, got string", func() {
+		s.router.Route("/a", func(c ctx.Context) string { return "" })
+	})
+	s.Panics(func() {
+		s.router.Route("/b", func(c ctx.Context) (string, string, error) { return "", "", nil })
+	})
+	s.Panics(func() {
+		s.router.Route("/c", func(c ctx.Context) (int, int, int, error) { return 0, 0, 0, nil })
+	})
+}
+
+func (s *RouterTestSuite) TestValidateHandler() {
+	cases := map[string]interface{}{
+		"Invalid handler for route /v/{id}: handler must be a function":                                            "not a func",
+		"Invalid handler for route /v/{id}: first argument must be ctx.Context, got int":                           func(id int) error { return nil },
+		"Invalid handler for route /v/{id}: handler takes no arguments but the route declares path variables {id}": func() {},
+		"Invalid handler for route /v/{id}: handler has 0 path arguments but the route declares 1: {id}": func(c ctx.Context) error {
+			return nil
+		},
+		"Invalid handler for route /v/{id}: argument 1 for path variable {id} has unsupported type []string": func(c ctx.Context, id []string) error {
+			return nil
+		},
+		"Invalid handler for route /v/{id}: argument 2 of type map[string]string is not a parameter struct, a registered service or a trailing body struct": func(c ctx.Context, id int, m map[string]string) error {
+			return nil
+		},
+		"Invalid handler for route /v/{id}: argument 2 of type router.createRequest is not a parameter struct, a registered service or a trailing body struct": func(c ctx.Context, id int, b createRequest, p listParams) error {
+			return nil
+		},
+		"Invalid handler for route /v/{id}: argument 2 of type struct { M map[string]string \"query:\\\"m\\\"\" }: field M has unsupported type map[string]string": func(c ctx.Context, id int, p struct {
+			M map[string]string `query:"m"`
+		}) error {
+			return nil
+		},
+	}
+
+	for msg, handler := range cases {
+		s.PanicsWithError(msg, func() { s.router.Route("/v/{id}", handler) })
+	}
+}
+
+func (s *RouterTestSuite) TestPathVarPattern() {
+	s.router.Route("/users/{id:[0-9]+}", func(c ctx.Context, id int) error {
+		return c.OK(id)
+	})
+
+	w := s.serve(httptest.NewRequest("GET", "/users/42", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("42\n", w.Body.String())
+}
+
+type counter struct {
+	count int
+}
+
+func newCounter() *counter { return &counter{} }
+
+func (s *RouterTestSuite) TestServiceArguments() {
+	s.container.Register(newCounter)
+	s.router.Route("/count/{by}", func(c ctx.Context, by int, p listParams, svc *counter, log ilog.Logger) (int, error) {
+		log.Debug("Incrementing counter")
+		svc.count += by
+		return svc.count, nil
+	})
+
+	s.serve(httptest.NewRequest("POST", "/count/2", nil))
+	w := s.serve(httptest.NewRequest("POST", "/count/3", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("5\n", w.Body.String())
+}
+
+// recordMiddleware appends name to calls every time it runs.
+func recordMiddleware(calls *[]string, name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *RouterTestSuite) TestRouteUse() {
+	var calls []string
+	s.router.Use(recordMiddleware(&calls, "router"))
+	s.router.Route("/protected", func(c ctx.Context) {
+		calls = append(calls, "handler")
+	}).Use(recordMiddleware(&calls, "first"), recordMiddleware(&calls, "second")).Use(recordMiddleware(&calls, "third"))
+	s.router.Route("/open", func(c ctx.Context) {
+		calls = append(calls, "handler")
+	})
+
+	s.serve(httptest.NewRequest("GET", "/protected", nil))
+	s.Equal([]string{"router", "first", "second", "third", "handler"}, calls)
+
+	calls = nil
+	s.serve(httptest.NewRequest("GET", "/open", nil))
+	s.Equal([]string{"router", "handler"}, calls)
+}
+
+func (s *RouterTestSuite) TestGroup() {
+	var calls []string
+	s.router.NamePrefix("api.")
+	s.router.Use(recordMiddleware(&calls, "router"))
+	s.router.Group("/admin", func(admin Router) {
+		admin.NamePrefix("admin.")
+		admin.Use(recordMiddleware(&calls, "admin"))
+		admin.Group("/users", func(users Router) {
+			users.NamePrefix("users.")
+			users.Use(recordMiddleware(&calls, "users"))
+			users.Route("/{id}", func(c ctx.Context, id int) (int, error) {
+				calls = append(calls, "handler")
+				return id, nil
+			}).Name("get").Use(recordMiddleware(&calls, "route"))
+		})
+		admin.Route("/stats", func(c ctx.Context) {
+			calls = append(calls, "handler")
+		}).Name("stats")
+	})
+	s.router.Route("/health", func(c ctx.Context) {
+		calls = append(calls, "handler")
+	}).Name("health")
+
+	w := s.serve(httptest.NewRequest("GET", "/admin/users/5", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("5\n", w.Body.String())
+	s.Equal([]string{"router", "admin", "users", "route", "handler"}, calls)
+
+	calls = nil
+	s.serve(httptest.NewRequest("GET", "/admin/stats", nil))
+	s.Equal([]string{"router", "admin", "handler"}, calls)
+
+	calls = nil
+	s.serve(httptest.NewRequest("GET", "/health", nil))
+	s.Equal([]string{"router", "handler"}, calls)
+
+	m := s.router.(*muxRouter).mux
+	s.NotNil(m.Get("api.admin.users.get"))
+	s.NotNil(m.Get("api.admin.stats"))
+	s.NotNil(m.Get("api.health"))
+}
+
+func (s *RouterTestSuite) TestNotFound() {
+	w := s.serve(httptest.NewRequest("GET", "/missing", nil))
+	s.Equal(http.StatusNotFound, w.Code)
+	s.Equal(ctx.ContentTypeJSON, w.Header().Get(ctx.HeaderContentType))
+	s.JSONEq(`{"error": "Not found"}`, w.Body.String())
+	s.NotEmpty(w.Header().Get(ctx.HeaderRequestID))
+
+	req := httptest.NewRequest("GET", "/missing", nil)
+	req.Header.Set(ctx.HeaderAccept, ctx.ContentTypeXML)
+	w = s.serve(req)
+	s.Equal(http.StatusNotFound, w.Code)
+	s.Contains(w.Body.String(), "<error>Not found</error>")
+}
+
+func (s *RouterTestSuite) TestMethodNotAllowed() {
+	s.router.Route("/items", func(c ctx.Context) {}).Methods("GET", "POST")
+	s.router.Route("/items", func(c ctx.Context) {}).Methods("PUT")
+
+	w := s.serve(httptest.NewRequest("DELETE", "/items", nil))
+	s.Equal(http.StatusMethodNotAllowed, w.Code)
+	s.Equal("GET, POST, PUT", w.Header().Get("Allow"))
+	s.JSONEq(`{"error": "Method not allowed"}`, w.Body.String())
+}
+
+func (s *RouterTestSuite) TestCustomNotFoundHandlers() {
+	s.router.Route("/items", func(c ctx.Context) {}).Methods("GET")
+	s.router.NotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	s.router.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	}))
+
+	s.Equal(http.StatusTeapot, s.serve(httptest.NewRequest("GET", "/missing", nil)).Code)
+
+	w := s.serve(httptest.NewRequest("POST", "/items", nil))
+	s.Equal(http.StatusConflict, w.Code)
+	s.Equal("GET", w.Header().Get("Allow"))
+}
+
+func (s *RouterTestSuite) TestURL() {
+	s.router.Group("/users", func(g Router) {
+		g.Route("/{id}", func(c ctx.Context, id int) {}).Name("user")
+	})
+
+	u, err := s.router.URL("user", "id", "5")
+	s.Require().NoError(err)
+	s.Equal("/users/5", u.String())
+
+	s.router.StripBasePath("/api")
+	u, err = s.router.URL("user", "id", "5")
+	s.Require().NoError(err)
+	s.Equal("/api/users/5", u.String())
+
+	_, err = s.router.URL("missing")
+	s.EqualError(err, `No route named "missing"`)
+
+	_, err = s.router.URL("user")
+	s.Error(err)
+}
+
+func (s *RouterTestSuite) TestAbsoluteURL() {
+	s.router.Route("/items/{id}", func(c ctx.Context, id int) {}).Name("item")
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "localhost:8080"
+	u, err := s.router.AbsoluteURL(req, "item", "id", "5")
+	s.Require().NoError(err)
+	s.Equal("http://localhost:8080/items/5", u.String())
+
+	req.Header.Set("X-Forwarded-Proto", "https")
+	u, err = s.router.AbsoluteURL(req, "item", "id", "5")
+	s.Require().NoError(err)
+	s.Equal("https://localhost:8080/items/5", u.String())
+
+	s.T().Setenv(CustomHostVariable, "https://api.example.com")
+	u, err = s.router.AbsoluteURL(req, "item", "id", "5")
+	s.Require().NoError(err)
+	s.Equal("https://api.example.com/items/5", u.String())
+}
+
+func (s *RouterTestSuite) TestContextURL() {
+	s.router.Route("/items/{id}", func(c ctx.Context, id int) {}).Name("item")
+	s.router.Route("/items", func(c ctx.Context) error {
+		location, err := c.AbsoluteURL("item", "id", "7")
+		if err != nil {
+			return err
+		}
+		c.Writer().Header().Set("Location", location)
+		path, err := c.URL("item", "id", "7")
+		if err != nil {
+			return err
+		}
+		return c.Respond(http.StatusCreated, map[string]string{"href": path})
+	}).Methods("POST")
+
+	s.router.StripBasePath("v1")
+	req := httptest.NewRequest("POST", "/items", nil)
+	req.Host = "example.com"
+	w := s.serve(req)
+	s.Equal(http.StatusCreated, w.Code)
+	s.Equal("http://example.com/v1/items/7", w.Header().Get("Location"))
+	s.JSONEq(`{"href": "/v1/items/7"}`, w.Body.String())
+}
+
+func tagMiddleware(next http.Handler) http.Handler {
+	return next
+}
+
+func (s *RouterTestSuite) TestRoutes() {
+	s.router.Route("/users/{id}", func(c ctx.Context, id int) {}).Methods("GET").Name("users.get")
+	s.router.Group("/admin", func(g Router) {
+		g.Use(tagMiddleware)
+		g.Route("/soap", func(c ctx.Context) {}).Methods("POST").Headers("SOAPAction", "ping").Use(RequestIDMiddleware)
+	})
+	s.router.Route("/raw", func(c ctx.Context) {}).Handler(http.NotFound)
+	s.router.ServeSPA("/app", "static")
+
+	s.Equal([]RouteInfo{
+		{
+			Name:       "users.get",
+			Path:       "/users/{id}",
+			Methods:    []string{"GET"},
+			Middleware: []string{"router.RequestIDMiddleware", "router.RecoveryMiddleware"},
+			Handler:    "router.(*RouterTestSuite).TestRoutes",
+		},
+		{
+			Path:       "/admin/soap",
+			Methods:    []string{"POST"},
+			Headers:    map[string]string{"SOAPAction": "ping"},
+			Middleware: []string{"router.RequestIDMiddleware", "router.RecoveryMiddleware", "router.tagMiddleware", "router.RequestIDMiddleware"},
+			Handler:    "router.(*RouterTestSuite).TestRoutes",
+		},
+		{
+			Path:       "/raw",
+			Middleware: []string{"router.RequestIDMiddleware", "router.RecoveryMiddleware"},
+			Handler:    "http.NotFound",
+		},
+		{
+			Path:       "/app",
+			Middleware: []string{"router.RequestIDMiddleware", "router.RecoveryMiddleware"},
+			Handler:    "router.spaHandler",
+		},
+	}, s.router.Routes())
+}
+
+type lazyService struct{}
+
+func (s *RouterTestSuite) TestServiceArgumentsConcurrent() {
+	var builds int32
+	s.container.Register(func() *lazyService {
+		atomic.AddInt32(&builds, 1)
+		return &lazyService{}
+	})
+	s.router.Route("/lazy", func(c ctx.Context, svc *lazyService) error {
+		return c.OK(svc != nil)
+	})
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 200)
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- s.serve(httptest.NewRequest("GET", "/lazy", nil)).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		s.Equal(http.StatusOK, code)
+	}
+	s.Equal(int32(1), atomic.LoadInt32(&builds))
+}
+
+func (s *RouterTestSuite) TestBodyNotMistakenForService() {
+	s.container.Register(func() *createRequest { return &createRequest{Name: "singleton"} })
+	s.router.Route("/users", func(c ctx.Context, body createRequest) error {
+		return c.OK(body.Name)
+	}).Methods("POST")
+
+	req := httptest.NewRequest("POST", "/users", bytes.NewBufferString(`{"name": "kyle"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := s.serve(req)
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("\"kyle\"\n", w.Body.String())
+}
+
+func (s *RouterTestSuite) TestServiceValuePointerMismatch() {
+	s.container.Register(newCounter)
+	msg := "Invalid handler for route /count: argument 1 of type router.counter is not a parameter struct, a registered service or a trailing body struct"
+	s.PanicsWithError(msg, func() {
+		s.router.Route("/count", func(c ctx.Context, svc counter, p listParams) error { return nil })
+	})
+}
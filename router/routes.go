@@ -0,0 +1,111 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// RouteInfo describes a registered route, see Router.Routes.
+type RouteInfo struct {
+	// Name is the full name of the route including any name prefix, or empty if the route is not named.
+	Name string `json:"name,omitempty"`
+
+	// Path is the path template, including the prefixes of the groups containing the route.
+	Path string `json:"path"`
+
+	// Methods lists the methods the route accepts, or is empty if it accepts every method.
+	Methods []string `json:"methods,omitempty"`
+
+	// Headers maps the headers the route requires to their value. An empty value only requires the header to be present.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Middleware lists the middleware that runs for the route, in order, starting with the middleware of the root router.
+	Middleware []string `json:"middleware"`
+
+	// Handler is the controller method or handler function serving the route.
+	Handler string `json:"handler"`
+}
+
+var closureSuffix = regexp.MustCompile(`(\.func\d+(\.\d+)*)+$`)
+
+func (r *muxRouter) Routes() []RouteInfo {
+	routes := []RouteInfo{}
+	r.mux.Walk(func(mr *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if mr.GetHandler() == nil {
+			// Subrouters are walked through the route holding their prefix, which has no handler of its own.
+			return nil
+		}
+
+		info := RouteInfo{Name: mr.GetName(), Middleware: []string{}}
+		info.Path, _ = mr.GetPathTemplate()
+		info.Methods, _ = mr.GetMethods()
+
+		route, ok := r.registry.routes[mr]
+		if !ok {
+			info.Handler = handlerName(mr.GetHandler())
+			info.Middleware = r.middlewareNames()
+			routes = append(routes, info)
+			return nil
+		}
+
+		if route.target != nil {
+			info.Handler = funcName(route.target.Method)
+		} else {
+			info.Handler = handlerName(route.handler)
+		}
+		for i := 0; i < len(route.headers)-1; i += 2 {
+			if info.Headers == nil {
+				info.Headers = map[string]string{}
+			}
+			info.Headers[route.headers[i]] = route.headers[i+1]
+		}
+		info.Middleware = route.router.middlewareNames()
+		for _, fn := range route.middleware {
+			info.Middleware = append(info.Middleware, funcName(reflect.ValueOf(fn)))
+		}
+		routes = append(routes, info)
+		return nil
+	})
+	return routes
+}
+
+// middlewareNames returns the names of the middleware added with Use to the router and its parents, starting with the
+// middleware the root router runs for every request.
+func (r *muxRouter) middlewareNames() []string {
+	names := []string{}
+	for router := r; router != nil; router = router.parent {
+		level := make([]string, 0, len(router.edge)+len(router.middleware))
+		for _, fn := range append(append([]Middleware{}, router.edge...), router.middleware...) {
+			level = append(level, funcName(reflect.ValueOf(fn)))
+		}
+		names = append(level, names...)
+	}
+	return names
+}
+
+// handlerName returns the name of the function behind an http.HandlerFunc, or the type of any other http.Handler.
+func handlerName(h http.Handler) string {
+	if fn, ok := h.(http.HandlerFunc); ok {
+		return funcName(reflect.ValueOf(fn))
+	}
+	return strings.TrimPrefix(reflect.TypeOf(h).String(), "*")
+}
+
+// funcName returns the name of a function qualified by the last element of its package path, such as
+// `controller.(*T).Add`. Closures are named after the function that created them, so the middleware returned by
+// AccessLogMiddleware is `router.AccessLogMiddleware`.
+func funcName(fn reflect.Value) string {
+	f := runtime.FuncForPC(fn.Pointer())
+	if f == nil {
+		return fn.Type().String()
+	}
+
+	name := strings.TrimSuffix(f.Name(), "-fm")
+	name = closureSuffix.ReplaceAllString(name, "")
+	return name[strings.LastIndex(name, "/")+1:]
+}
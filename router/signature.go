@@ -0,0 +1,155 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kgrunwald/goweb/ctx"
+	"github.com/kgrunwald/goweb/di"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*ctx.Context)(nil)).Elem()
+)
+
+// paramKind describes where the value for a controller method argument comes from.
+type paramKind int
+
+const (
+	paramInvalid paramKind = iota
+	paramContext
+	paramPath
+	paramStruct
+	paramService
+	paramBody
+)
+
+// classifyParam determines how the argument at idx of the controller method is populated. The first argument is the
+// ctx.Context, followed by one argument for each of the numVars path variables. Any remaining arguments must be
+// parameter structs or services registered in the container with exactly their type, and the final argument may be a
+// body struct. A body struct `Foo` is therefore never taken for a service registered as `*Foo`.
+func classifyParam(method reflect.Type, idx, numVars int, container di.Container) paramKind {
+	argType := method.In(idx)
+	switch {
+	case idx == 0:
+		if argType == contextType {
+			return paramContext
+		}
+	case idx <= numVars:
+		if canConvert(argType) {
+			return paramPath
+		}
+	case isParamsStruct(argType):
+		return paramStruct
+	case container != nil && container.Has(argType):
+		return paramService
+	case idx == method.NumIn()-1 && isBodyStruct(argType):
+		return paramBody
+	}
+	return paramInvalid
+}
+
+// validateReturns checks that a controller method returns nothing, `error`, `(T, error)` or `(int, T, error)`.
+func validateReturns(method reflect.Type) error {
+	numOut := method.NumOut()
+	if numOut == 0 {
+		return nil
+	}
+	if numOut > 3 {
+		return fmt.Errorf("handler returns %d values, expected at most 3", numOut)
+	}
+	if method.Out(numOut-1) != errorType {
+		return fmt.Errorf("last return value must be error, got %s", method.Out(numOut-1))
+	}
+	if numOut == 3 {
+		switch method.Out(0).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		default:
+			return fmt.Errorf("first of three return values must be an int status code, got %s", method.Out(0))
+		}
+	}
+	return nil
+}
+
+// validateParams checks every argument of the controller method against the variables of the route template.
+func validateParams(method reflect.Type, vars []string, container di.Container) error {
+	numIn := method.NumIn()
+	if numIn == 0 {
+		if len(vars) > 0 {
+			return fmt.Errorf("handler takes no arguments but the route declares path variables {%s}", strings.Join(vars, "}, {"))
+		}
+		return nil
+	}
+
+	if classifyParam(method, 0, len(vars), container) != paramContext {
+		return fmt.Errorf("first argument must be ctx.Context, got %s", method.In(0))
+	}
+
+	if numIn-1 < len(vars) {
+		return fmt.Errorf("handler has %d path arguments but the route declares %d: {%s}", numIn-1, len(vars), strings.Join(vars, "}, {"))
+	}
+
+	for idx := 1; idx < numIn; idx++ {
+		argType := method.In(idx)
+		switch classifyParam(method, idx, len(vars), container) {
+		case paramInvalid:
+			if idx <= len(vars) {
+				return fmt.Errorf("argument %d for path variable {%s} has unsupported type %s", idx, vars[idx-1], argType)
+			}
+			return fmt.Errorf("argument %d of type %s is not a parameter struct, a registered service or a trailing body struct", idx, argType)
+		case paramStruct:
+			if err := validateParamsStruct(argType); err != nil {
+				return fmt.Errorf("argument %d of type %s: %v", idx, argType, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateParamsStruct checks that every tagged field of a parameter struct is exported and convertible.
+func validateParamsStruct(t reflect.Type) error {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if _, _, ok := paramTag(field); !ok {
+			continue
+		}
+		if field.PkgPath != "" {
+			return fmt.Errorf("tagged field %s is not exported", field.Name)
+		}
+
+		fieldType := field.Type
+		if _, ok := getConverter(fieldType); !ok && fieldType.Kind() == reflect.Slice {
+			fieldType = fieldType.Elem()
+		}
+		if !canConvert(fieldType) {
+			return fmt.Errorf("field %s has unsupported type %s", field.Name, field.Type)
+		}
+	}
+	return nil
+}
+
+// validateHandler verifies that the route target can be invoked by a RouteHandler. It returns a descriptive error naming
+// the route if it cannot.
+func validateHandler(path string, method reflect.Value, vars []string, container di.Container) error {
+	if err := checkHandler(method, vars, container); err != nil {
+		return fmt.Errorf("Invalid handler for route %s: %v", path, err)
+	}
+	return nil
+}
+
+func checkHandler(method reflect.Value, vars []string, container di.Container) error {
+	if !method.IsValid() || method.Kind() != reflect.Func {
+		return errors.New("handler must be a function")
+	}
+	if err := validateParams(method.Type(), vars, container); err != nil {
+		return err
+	}
+	return validateReturns(method.Type())
+}
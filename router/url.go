@@ -0,0 +1,69 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+func (r *muxRouter) URL(name string, params ...string) (*url.URL, error) {
+	route := r.mux.Get(name)
+	if route == nil {
+		return nil, fmt.Errorf("No route named %q", name)
+	}
+
+	u, err := route.URLPath(params...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not build URL for route %q: %v", name, err)
+	}
+
+	if len(r.stripBasePath) > 1 {
+		u.Path = r.stripBasePath + u.Path
+		if u.RawPath != "" {
+			u.RawPath = r.stripBasePath + u.RawPath
+		}
+	}
+	return u, nil
+}
+
+func (r *muxRouter) AbsoluteURL(req *http.Request, name string, params ...string) (*url.URL, error) {
+	u, err := r.URL(name, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(baseURL(req))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid $%s: %v", CustomHostVariable, err)
+	}
+	u.Scheme = base.Scheme
+	u.Host = base.Host
+	return u, nil
+}
+
+// baseURL returns the scheme and host clients use to reach the application. CustomHostVariable takes precedence. Lambda
+// requests carry the scheme and host in their URL, while for HTTP requests they come from the connection, the
+// X-Forwarded-Proto header and the Host header.
+func baseURL(req *http.Request) string {
+	if custom, ok := os.LookupEnv(CustomHostVariable); ok {
+		return custom
+	}
+
+	scheme := req.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if req.TLS != nil {
+			scheme = "https"
+		}
+		if proto := req.Header.Get("X-Forwarded-Proto"); proto != "" {
+			scheme = proto
+		}
+	}
+
+	host := req.URL.Host
+	if host == "" {
+		host = req.Host
+	}
+	return scheme + "://" + host
+}
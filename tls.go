@@ -0,0 +1,122 @@
+package goweb
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kgrunwald/goweb/ilog"
+)
+
+// Environment variables used to configure HTTPS. When $TLS_CERT_FILE and $TLS_KEY_FILE are set the key pair is loaded
+// from disk. When $TLS_SELF_SIGNED is `true` a certificate for localhost is generated on startup, which is only
+// suitable for local development. $HTTP_REDIRECT_PORT starts a second plain HTTP listener that redirects to HTTPS.
+const (
+	EnvTLSCertFile      = "TLS_CERT_FILE"
+	EnvTLSKeyFile       = "TLS_KEY_FILE"
+	EnvTLSSelfSigned    = "TLS_SELF_SIGNED"
+	EnvHTTPRedirectPort = "HTTP_REDIRECT_PORT"
+)
+
+// getTLSConfig builds the TLS configuration for the server from the environment. It returns nil if HTTPS is not
+// configured. HTTP/2 is negotiated through ALPN.
+func getTLSConfig(log ilog.Logger) *tls.Config {
+	certFile := os.Getenv(EnvTLSCertFile)
+	keyFile := os.Getenv(EnvTLSKeyFile)
+
+	var cert tls.Certificate
+	var err error
+	switch {
+	case certFile != "" || keyFile != "":
+		cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			log.WithFields("cert", certFile, "key", keyFile, "error", err).Fatal("Failed to load TLS key pair")
+		}
+	case os.Getenv(EnvTLSSelfSigned) == "true":
+		log.Warn("Generating a self-signed TLS certificate, this should only be used for local development")
+		cert, err = selfSignedCertificate()
+		if err != nil {
+			log.WithField("error", err).Fatal("Failed to generate self-signed TLS certificate")
+		}
+	default:
+		return nil
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+}
+
+func getRedirectPort(log ilog.Logger) int {
+	val := os.Getenv(EnvHTTPRedirectPort)
+	if val == "" {
+		return 0
+	}
+
+	port, err := strconv.Atoi(val)
+	if err != nil {
+		log.WithFields("variable", EnvHTTPRedirectPort, "value", val).Fatal("Invalid redirect port")
+	}
+	return port
+}
+
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"goweb local development"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// redirectHandler sends every request to the same host and path on the HTTPS port.
+func redirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+
+		target := *r.URL
+		target.Scheme = "https"
+		target.Host = host
+		http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,110 @@
+package goweb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgrunwald/goweb/ilog"
+	"github.com/stretchr/testify/suite"
+)
+
+type TLSTestSuite struct {
+	suite.Suite
+}
+
+func TestTLSTestSuite(t *testing.T) {
+	suite.Run(t, new(TLSTestSuite))
+}
+
+func (s *TLSTestSuite) TestRedirectHandler() {
+	cases := []struct {
+		port     int
+		url      string
+		expected string
+	}{
+		{443, "http://example.com/users?page=2&tag=a", "https://example.com/users?page=2&tag=a"},
+		{443, "http://example.com:8080/", "https://example.com/"},
+		{8443, "http://example.com/users?page=2", "https://example.com:8443/users?page=2"},
+		{8443, "http://localhost:8080/a/b", "https://localhost:8443/a/b"},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		redirectHandler(c.port).ServeHTTP(w, httptest.NewRequest("GET", c.url, nil))
+		s.Equal(http.StatusMovedPermanently, w.Code, c.url)
+		s.Equal(c.expected, w.Header().Get("Location"), c.url)
+	}
+}
+
+func (s *TLSTestSuite) TestNoTLSConfig() {
+	s.T().Setenv(EnvTLSCertFile, "")
+	s.T().Setenv(EnvTLSKeyFile, "")
+	s.T().Setenv(EnvTLSSelfSigned, "")
+	s.Nil(getTLSConfig(ilog.NewLogger()))
+}
+
+// serveTLS serves a request with config and returns the protocol the client negotiated.
+func (s *TLSTestSuite) serveTLS(config *tls.Config) string {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.EnableHTTP2 = true
+	server.TLS = config
+	server.StartTLS()
+	defer server.Close()
+
+	cert, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	s.Require().NoError(err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get(server.URL)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	s.Equal("ok", string(body))
+	return resp.Proto
+}
+
+func (s *TLSTestSuite) TestSelfSignedCertificate() {
+	s.T().Setenv(EnvTLSCertFile, "")
+	s.T().Setenv(EnvTLSKeyFile, "")
+	s.T().Setenv(EnvTLSSelfSigned, "true")
+
+	config := getTLSConfig(ilog.NewLogger())
+	s.Require().NotNil(config)
+	s.Equal(uint16(tls.VersionTLS12), config.MinVersion)
+	s.Equal("HTTP/2.0", s.serveTLS(config))
+}
+
+func (s *TLSTestSuite) TestCertificateFiles() {
+	cert, err := selfSignedCertificate()
+	s.Require().NoError(err)
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	s.Require().NoError(err)
+
+	dir := s.T().TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	s.Require().NoError(ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600))
+	s.Require().NoError(ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600))
+
+	s.T().Setenv(EnvTLSCertFile, certFile)
+	s.T().Setenv(EnvTLSKeyFile, keyFile)
+	s.T().Setenv(EnvTLSSelfSigned, "")
+
+	config := getTLSConfig(ilog.NewLogger())
+	s.Require().NotNil(config)
+	s.Equal("HTTP/2.0", s.serveTLS(config))
+}